@@ -2,11 +2,15 @@ package main
 
 import (
 	config "afrus-whatsapp-evolution_api-notification/configs"
+	"afrus-whatsapp-evolution_api-notification/internal/application/repositories"
+	"afrus-whatsapp-evolution_api-notification/internal/domain/models"
+	"afrus-whatsapp-evolution_api-notification/internal/server"
 	"afrus-whatsapp-evolution_api-notification/internal/services"
 	"afrus-whatsapp-evolution_api-notification/internal/usecase"
 	"afrus-whatsapp-evolution_api-notification/pkg/db"
 	"afrus-whatsapp-evolution_api-notification/pkg/queue"
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -16,6 +20,7 @@ import (
 	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
+	"gorm.io/gorm"
 )
 
 func main() {
@@ -54,7 +59,7 @@ func main() {
 		SSLMode:  conf.EventsDBSSLMode,
 	}
 
-	if err := dbManager.Connect(db.EventsDB, eventsConfig); // &events.Sent{}, &events.Accepted{}, &events.Canceled{}, &events.Delivered{}, &events.Failed{}, &events.PartiallyDelivered{}, &events.Queued{}, &events.Read{}, &events.Scheduled{}
+	if err := dbManager.Connect(db.EventsDB, eventsConfig, &models.IdempotencyKey{}, &models.ShortLink{}, &models.LinkClick{}, &models.Suppression{}, &models.OrganizationSettings{}, &models.NumberCheck{}, &models.LeadSender{}, &models.RateLimitPolicy{}, &models.InstanceWarmup{}, &models.BlastProgress{}, &models.BlastProgressLead{}, &models.PendingStatusEvent{}); // &events.Sent{}, &events.Accepted{}, &events.Canceled{}, &events.Delivered{}, &events.Failed{}, &events.PartiallyDelivered{}, &events.Queued{}, &events.Read{}, &events.Scheduled{}
 	err != nil {
 		panic(fmt.Sprintf("Failed to connect to Events database: %v", err))
 	}
@@ -69,43 +74,68 @@ func main() {
 
 	rabbitMQ := queue.NewRabbitMQ(conf)
 
-	blastsMessages, err := rabbitMQ.AddQueue(queue.QueueConfig{
-		Name:       conf.EvolutionAPINotificationBlastQueue,
-		BufferSize: 10,
-		Consumer:   "blast-consumer",
-	})
+	blastQueueConfig := queue.QueueConfig{
+		Name:          conf.EvolutionAPINotificationBlastQueue,
+		BufferSize:    10,
+		Consumer:      "blast-consumer",
+		PrefetchCount: conf.EvolutionAPINotificationBlastPrefetch,
+		Workers:       conf.EvolutionAPINotificationBlastWorkers,
+	}
+
+	blastsMessages, err := rabbitMQ.AddQueue(blastQueueConfig)
 	if err != nil {
 		log.Fatalf("[RABBITMQ] - Error adding blast queue: %v", err)
 	}
 
-	autoresponderMessages, err := rabbitMQ.AddQueue(queue.QueueConfig{
-		Name:       conf.EvolutionAPINotificationAutoresponderQueue,
-		BufferSize: 100,
-		Consumer:   "autoresponder-consumer",
-	})
+	autoresponderQueueConfig := queue.QueueConfig{
+		Name:          conf.EvolutionAPINotificationAutoresponderQueue,
+		BufferSize:    100,
+		Consumer:      "autoresponder-consumer",
+		PrefetchCount: conf.EvolutionAPINotificationAutoresponderPrefetch,
+		Workers:       conf.EvolutionAPINotificationAutoresponderWorkers,
+	}
+
+	autoresponderMessages, err := rabbitMQ.AddQueue(autoresponderQueueConfig)
 	if err != nil {
 		log.Fatalf("[RABBITMQ] - Error adding autoresponder queue: %v", err)
 	}
 
+	if conf.RabbitMQDeclareTopology {
+		rabbitMQ.SetTopology(queue.NewTopology(conf))
+	}
+
 	if err := rabbitMQ.Connect(); err != nil {
 		log.Fatalf("[RABBITMQ] - Error connecting to RabbitMQ: %v", err)
 	}
 	defer rabbitMQ.Close()
 
-	whatsappSenderService := services.NewWhatsappSenderService(conf)
+	whatsappSenderService := services.NewWhatsappSenderService(conf, repositories.NewNumberCheckRepository(databases.EventsDB))
 
-	go processBlastEvent(conf, blastsMessages, databases, rabbitMQ, whatsappSenderService)
-	go processAutoresponderEvent(conf, autoresponderMessages, databases, rabbitMQ, whatsappSenderService)
+	retryBaseDelay := time.Duration(conf.RabbitMQRetryBaseDelaySeconds) * time.Second
+	blastRetryPolicy := queue.NewRetryPolicy(conf.RabbitMQRetryMaxAttempts, retryBaseDelay, conf.EvolutionAPINotificationExchange, conf.EvolutionAPINotificationBlastRoutingKey, conf.RabbitMQDeadLetterExchange, conf.RabbitMQDeadLetterRoutingKey)
+	autoresponderRetryPolicy := queue.NewRetryPolicy(conf.RabbitMQRetryMaxAttempts, retryBaseDelay, conf.EvolutionAPINotificationExchange, conf.EvolutionAPINotificationAutoresponderRoutingKey, conf.RabbitMQDeadLetterExchange, conf.RabbitMQDeadLetterRoutingKey)
 
-	if err := waitForShutdown(ctx, cancel, errChan, rabbitMQ); err != nil {
+	go processBlastEvent(conf, blastsMessages, blastQueueConfig.Concurrency(), databases, rabbitMQ, whatsappSenderService, blastRetryPolicy)
+	go processAutoresponderEvent(conf, autoresponderMessages, autoresponderQueueConfig.Concurrency(), databases, rabbitMQ, whatsappSenderService, autoresponderRetryPolicy)
+
+	go cleanupExpiredRecords(ctx, databases.EventsDB)
+	go monitorInstanceHealth(ctx, conf, databases, whatsappSenderService)
+
+	httpServer := server.NewServer(conf, databases, whatsappSenderService)
+	go func() {
+		if err := httpServer.Start(); err != nil {
+			errChan <- err
+		}
+	}()
+
+	if err := waitForShutdown(ctx, cancel, errChan, rabbitMQ, httpServer); err != nil {
 		log.Fatalf("[SHUTDOWN] - Error during shutdown: %v", err)
 	}
 
 }
 
-func processAutoresponderEvent(config *config.Config, msgs <-chan *amqp.Delivery, databases *db.DBConnections, rabbitMQ *queue.RabbitMQ, service *services.WhatsappSenderService) {
+func processAutoresponderEvent(config *config.Config, msgs <-chan *amqp.Delivery, numWorkers int, databases *db.DBConnections, rabbitMQ *queue.RabbitMQ, service *services.WhatsappSenderService, retryPolicy queue.RetryPolicy) {
 	var wg sync.WaitGroup
-	numWorkers := 300
 	workerPool := make(chan struct{}, numWorkers)
 
 	for msg := range msgs {
@@ -123,8 +153,7 @@ func processAutoresponderEvent(config *config.Config, msgs <-chan *amqp.Delivery
 
 			handler := usecase.NewReceiptAutoresponderEventUseCase(ctx, config, rabbitMQ, databases.Afrus, databases.EventsDB, service)
 			if err := handler.Execute(string(msg.Body)); err != nil {
-				log.Printf("[ERROR] - Error processing message: %v", err)
-				msg.Nack(false, false)
+				handleFailedMessage(ctx, rabbitMQ, msg, retryPolicy, err, handler.DeadLettered)
 				return
 			}
 
@@ -138,9 +167,8 @@ func processAutoresponderEvent(config *config.Config, msgs <-chan *amqp.Delivery
 	close(workerPool)
 }
 
-func processBlastEvent(config *config.Config, msgs <-chan *amqp.Delivery, databases *db.DBConnections, rabbitMQ *queue.RabbitMQ, service *services.WhatsappSenderService) {
+func processBlastEvent(config *config.Config, msgs <-chan *amqp.Delivery, numWorkers int, databases *db.DBConnections, rabbitMQ *queue.RabbitMQ, service *services.WhatsappSenderService, retryPolicy queue.RetryPolicy) {
 	var wg sync.WaitGroup
-	numWorkers := 300
 	workerPool := make(chan struct{}, numWorkers)
 
 	for msg := range msgs {
@@ -158,8 +186,7 @@ func processBlastEvent(config *config.Config, msgs <-chan *amqp.Delivery, databa
 
 			handler := usecase.NewReceiptBlastEventUseCase(ctx, config, rabbitMQ, databases.Afrus, databases.EventsDB, service)
 			if err := handler.Execute(string(msg.Body)); err != nil {
-				log.Printf("[ERROR] - Error processing message: %v", err)
-				msg.Nack(false, false)
+				handleFailedMessage(ctx, rabbitMQ, msg, retryPolicy, err, handler.DeadLettered)
 				return
 			}
 
@@ -173,7 +200,104 @@ func processBlastEvent(config *config.Config, msgs <-chan *amqp.Delivery, databa
 	close(workerPool)
 }
 
-func waitForShutdown(ctx context.Context, cancel context.CancelFunc, errChan <-chan error, rabbit *queue.RabbitMQ) error {
+// cleanupExpiredRecords deletes the idempotency keys, parked status events
+// and cached number checks that expired.
+func cleanupExpiredRecords(ctx context.Context, eventsDB *gorm.DB) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	idempotencyKeyRepo := repositories.NewIdempotencyKeyRepository(eventsDB)
+	pendingStatusRepo := repositories.NewPendingStatusEventRepository(eventsDB)
+	numberCheckRepo := repositories.NewNumberCheckRepository(eventsDB)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := idempotencyKeyRepo.DeleteExpired(ctx)
+			if err != nil {
+				log.Printf("[ERROR] - Error deleting expired idempotency keys: %v", err)
+			} else {
+				log.Printf("[INFO] - Deleted %d expired idempotency keys", deleted)
+			}
+
+			deleted, err = pendingStatusRepo.DeleteExpired(ctx)
+			if err != nil {
+				log.Printf("[ERROR] - Error deleting expired parked status events: %v", err)
+			} else {
+				log.Printf("[INFO] - Deleted %d expired parked status events", deleted)
+			}
+
+			deleted, err = numberCheckRepo.DeleteExpired(ctx)
+			if err != nil {
+				log.Printf("[ERROR] - Error deleting expired number checks: %v", err)
+			} else {
+				log.Printf("[INFO] - Deleted %d expired number checks", deleted)
+			}
+		}
+	}
+}
+
+// monitorInstanceHealth refreshes the connection state of every instance so
+// the senders can skip disconnected ones.
+func monitorInstanceHealth(ctx context.Context, config *config.Config, databases *db.DBConnections, service *services.WhatsappSenderService) {
+	interval := time.Duration(config.InstanceHealthCheckIntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	handler := usecase.NewInstanceHealthUseCase(ctx, config, databases.Afrus, databases.EventsDB, service)
+
+	for {
+		if err := handler.Execute(); err != nil {
+			log.Printf("[ERROR] - Error checking instance health: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// handleFailedMessage settles a delivery whose processing failed: rescheduled
+// messages are acked as-is, anything else goes through the retry policy and
+// is only dropped when it cannot be re-published. deadLettered, when set,
+// records the failure of a message that won't be retried.
+func handleFailedMessage(ctx context.Context, rabbitMQ *queue.RabbitMQ, msg *amqp.Delivery, retryPolicy queue.RetryPolicy, err error, deadLettered func(event string, cause error) error) {
+	if errors.Is(err, usecase.ErrMessageRescheduled) {
+		log.Printf("[INFO] - %v", err)
+	} else {
+		log.Printf("[ERROR] - Error processing message: %v", err)
+
+		// Dropped or dead-lettered, the message is final either way
+		if deadLettered != nil && retryPolicy.Exhausted(msg.Headers, err) {
+			if deadErr := deadLettered(string(msg.Body), err); deadErr != nil {
+				log.Printf("[ERROR] - Error recording dead-lettered message: %v", deadErr)
+			}
+		}
+
+		// The retry reserves its send again instead of trusting a stale slot
+		retried := *msg
+		retried.Body = usecase.WithoutPacing(msg.Body)
+		if retryErr := rabbitMQ.Retry(ctx, &retried, retryPolicy, err); retryErr != nil {
+			log.Printf("[ERROR] - Error retrying message: %v", retryErr)
+			msg.Nack(false, false)
+			return
+		}
+	}
+
+	if err := msg.Ack(false); err != nil {
+		log.Printf("[ERROR] - Error acknowledging message: %v", err)
+	}
+}
+
+func waitForShutdown(ctx context.Context, cancel context.CancelFunc, errChan <-chan error, rabbit *queue.RabbitMQ, httpServer *server.Server) error {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
@@ -191,6 +315,10 @@ func waitForShutdown(ctx context.Context, cancel context.CancelFunc, errChan <-c
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer shutdownCancel()
 
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		log.Printf("[SHUTDOWN] - Error shutting down HTTP server: %v", err)
+	}
+
 	// Wait for RabbitMQ connections and consumers to be cleaned up
 	done := make(chan struct{})
 	go func() {
@@ -1,9 +1,14 @@
 package dto
 
+import "time"
+
 type AutoresponderEventProcess struct {
 	Content            string `json:"content"`
 	LeadID             int    `json:"lead_id"`
 	OrganizationID     int    `json:"organization_id"`
 	WhatsappTriggerID  int    `json:"whatsapp_trigger_id"`
 	WhatsappInstanceID int    `json:"whatsapp_instance_id"`
+	// Set when the message was paced: the send is already reserved on
+	// WhatsappInstanceID and must not leave before NotBefore.
+	NotBefore *time.Time `json:"not_before,omitempty"`
 }
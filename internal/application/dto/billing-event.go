@@ -0,0 +1,29 @@
+package dto
+
+import "fmt"
+
+const (
+	BillingEventVersion = 1
+	BillingChannel      = "whatsapp"
+)
+
+type BillingEvent struct {
+	Version         int    `json:"version"`
+	IdempotencyKey  string `json:"idempotencyKey"`
+	Channel         string `json:"channel"`
+	OrganizationID  int    `json:"organizationId"`
+	LeadID          int    `json:"leadId"`
+	SourceTable     string `json:"sourceTable"`
+	SourceID        int    `json:"sourceId"`
+	MessageID       string `json:"messageId"`
+	InstanceID      uint   `json:"instanceId"`
+	InstanceName    string `json:"instanceName"`
+	AttachmentCount int    `json:"attachmentCount"`
+	Timestamp       string `json:"timestamp"`
+}
+
+// BillingIdempotencyKey identifies a single charge so the billing consumer can
+// discard redelivered events.
+func BillingIdempotencyKey(sourceTable string, sourceID, leadID int, messageID string) string {
+	return fmt.Sprintf("%s:%s:%d:%d:%s", BillingChannel, sourceTable, sourceID, leadID, messageID)
+}
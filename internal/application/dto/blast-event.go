@@ -1,8 +1,18 @@
 package dto
 
+import "time"
+
 type BlastEventProcess struct {
 	Content                 string `json:"content"`
 	LeadID                  int    `json:"leadId"`
 	CommunicationWhatsappId int    `json:"communicationWhatsappId"`
 	OrganizationID          int    `json:"organizationId"`
+	// ExpectedLeads is the number of leads the blast was sent to, used to
+	// detect when it finishes. When missing the leads of the communication
+	// are counted instead.
+	ExpectedLeads int `json:"expectedLeads,omitempty"`
+	// Set when the message was paced: the send is already reserved on
+	// PacedInstanceID and must not leave before NotBefore.
+	PacedInstanceID int        `json:"pacedInstanceId,omitempty"`
+	NotBefore       *time.Time `json:"notBefore,omitempty"`
 }
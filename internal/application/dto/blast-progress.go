@@ -0,0 +1,18 @@
+package dto
+
+import "time"
+
+// BlastProgressReport is the progress of the messages of a blast. Pending is
+// zero while the expected count is unknown.
+type BlastProgressReport struct {
+	CommunicationWhatsappID int        `json:"communicationWhatsappId"`
+	OrganizationID          int        `json:"organizationId"`
+	Expected                int        `json:"expected"`
+	Sent                    int        `json:"sent"`
+	Failed                  int        `json:"failed"`
+	Canceled                int        `json:"canceled"`
+	Pending                 int        `json:"pending"`
+	Completed               bool       `json:"completed"`
+	CompletedAt             *time.Time `json:"completedAt"`
+	UpdatedAt               time.Time  `json:"updatedAt"`
+}
@@ -0,0 +1,147 @@
+package dto
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+const (
+	EvolutionEventMessagesUpdate = "messages.update"
+	EvolutionEventMessagesUpsert = "messages.upsert"
+	EvolutionEventSendMessage    = "send.message"
+)
+
+type EvolutionWebhookKey struct {
+	RemoteJid string `json:"remoteJid"`
+	FromMe    bool   `json:"fromMe"`
+	ID        string `json:"id"`
+}
+
+type EvolutionWebhookExtendedText struct {
+	Text string `json:"text"`
+}
+
+type EvolutionWebhookMessage struct {
+	Conversation        string                        `json:"conversation"`
+	ExtendedTextMessage *EvolutionWebhookExtendedText `json:"extendedTextMessage"`
+}
+
+type EvolutionWebhookData struct {
+	Key       *EvolutionWebhookKey     `json:"key"`
+	KeyID     string                   `json:"keyId"`
+	ID        string                   `json:"id"`
+	RemoteJid string                   `json:"remoteJid"`
+	FromMe    bool                     `json:"fromMe"`
+	Status    EvolutionStatus          `json:"status"`
+	PushName  string                   `json:"pushName"`
+	Message   *EvolutionWebhookMessage `json:"message"`
+}
+
+// Text returns the body of an inbound text message.
+func (d EvolutionWebhookData) Text() string {
+	if d.Message == nil {
+		return ""
+	}
+	if d.Message.Conversation != "" {
+		return d.Message.Conversation
+	}
+	if d.Message.ExtendedTextMessage != nil {
+		return d.Message.ExtendedTextMessage.Text
+	}
+	return ""
+}
+
+// SenderPhone returns the phone number of a direct (non-group) chat.
+func (d EvolutionWebhookData) SenderPhone() string {
+	jid := d.RemoteJid
+	if d.Key != nil && d.Key.RemoteJid != "" {
+		jid = d.Key.RemoteJid
+	}
+	phone, server, _ := strings.Cut(jid, "@")
+	if server != "s.whatsapp.net" && server != "c.us" {
+		return ""
+	}
+	return phone
+}
+
+// IsFromMe reports whether the message was sent by the instance itself.
+func (d EvolutionWebhookData) IsFromMe() bool {
+	if d.Key != nil {
+		return d.Key.FromMe
+	}
+	return d.FromMe
+}
+
+// MessageID returns the WhatsApp message id regardless of the payload
+// flavour sent by the Evolution API version in use.
+func (d EvolutionWebhookData) MessageID() string {
+	if d.Key != nil && d.Key.ID != "" {
+		return d.Key.ID
+	}
+	if d.KeyID != "" {
+		return d.KeyID
+	}
+	return d.ID
+}
+
+type EvolutionWebhookEvent struct {
+	Event    string               `json:"event"`
+	Instance string               `json:"instance"`
+	Data     EvolutionWebhookData `json:"data"`
+	DateTime string               `json:"date_time"`
+	// Sender is the JID of the number connected to the instance
+	Sender string `json:"sender"`
+}
+
+// SenderPhone returns the phone number connected to the instance that
+// emitted the event.
+func (e EvolutionWebhookEvent) SenderPhone() string {
+	return JIDPhone(e.Sender)
+}
+
+// JIDPhone returns the phone number of a WhatsApp JID, without the server and
+// the device suffix ("5511999999999:12@s.whatsapp.net").
+func JIDPhone(jid string) string {
+	user, _, _ := strings.Cut(jid, "@")
+	phone, _, _ := strings.Cut(user, ":")
+	return phone
+}
+
+// EventName normalizes the event name, since Evolution emits
+// "MESSAGES_UPDATE" when webhooks are split by event.
+func (e EvolutionWebhookEvent) EventName() string {
+	return strings.ReplaceAll(strings.ToLower(e.Event), "_", ".")
+}
+
+// EvolutionStatus accepts both the string ("DELIVERY_ACK") and the numeric
+// (3) status representations emitted by Evolution/Baileys.
+type EvolutionStatus string
+
+var evolutionNumericStatus = map[int]EvolutionStatus{
+	0: "ERROR",
+	1: "PENDING",
+	2: "SERVER_ACK",
+	3: "DELIVERY_ACK",
+	4: "READ",
+	5: "PLAYED",
+}
+
+func (s *EvolutionStatus) UnmarshalJSON(b []byte) error {
+	var str string
+	if err := json.Unmarshal(b, &str); err == nil {
+		if n, convErr := strconv.Atoi(str); convErr == nil {
+			*s = evolutionNumericStatus[n]
+			return nil
+		}
+		*s = EvolutionStatus(str)
+		return nil
+	}
+
+	var n int
+	if err := json.Unmarshal(b, &n); err != nil {
+		return err
+	}
+	*s = evolutionNumericStatus[n]
+	return nil
+}
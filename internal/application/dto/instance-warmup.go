@@ -0,0 +1,19 @@
+package dto
+
+import "time"
+
+// InstanceWarmupReport is the warm-up state of an instance for the current
+// (UTC) day.
+type InstanceWarmupReport struct {
+	WhatsappInstanceID uint       `json:"whatsappInstanceId"`
+	InstanceName       string     `json:"instanceName"`
+	ConnectionState    string     `json:"connectionState"`
+	Level              int        `json:"level"`
+	MaxLevel           int        `json:"maxLevel"`
+	DailyBudget        int        `json:"dailyBudget"`
+	SentToday          int        `json:"sentToday"`
+	FailedToday        int        `json:"failedToday"`
+	RemainingToday     int        `json:"remainingToday"`
+	DemotedAt          *time.Time `json:"demotedAt"`
+	DemotionReason     string     `json:"demotionReason"`
+}
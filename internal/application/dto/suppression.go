@@ -0,0 +1,12 @@
+package dto
+
+// SuppressionRequest suppresses a lead or a phone. Blocking the whole
+// organization takes neither and Scope "organization", so a misspelled field
+// can't stop every send.
+type SuppressionRequest struct {
+	OrganizationID int     `json:"organizationId"`
+	LeadID         *int    `json:"leadId"`
+	Phone          *string `json:"phone"`
+	Scope          string  `json:"scope"`
+	Reason         string  `json:"reason"`
+}
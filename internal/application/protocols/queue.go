@@ -3,6 +3,7 @@ package protocols
 import (
 	"afrus-whatsapp-evolution_api-notification/pkg/queue"
 	"context"
+	"time"
 )
 
 type Queue interface {
@@ -12,5 +13,5 @@ type Queue interface {
 	AddQueue(config queue.QueueConfig) (chan *interface{}, error)
 	startConsuming(config queue.QueueConfig, msgs chan *interface{}) error
 	Publish(ctx context.Context, exchange, routingKey string, body []byte) error
-	Schedule(exchange, routingKey string, body []byte, delay int) error
+	Schedule(ctx context.Context, exchange, routingKey string, body []byte, delay time.Duration) error
 }
@@ -0,0 +1,133 @@
+package repositories
+
+import (
+	"afrus-whatsapp-evolution_api-notification/internal/domain/models"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type BlastProgressRepository struct {
+	DB *gorm.DB
+}
+
+type BlastProgressRepositoryInterface interface {
+	Increment(ctx context.Context, organizationID, communicationWhatsappID, leadID, expected int, outcome string) (*models.BlastProgress, bool, error)
+	FindByCommunication(ctx context.Context, communicationWhatsappID int) (*models.BlastProgress, error)
+}
+
+func NewBlastProgressRepository(db *gorm.DB) *BlastProgressRepository {
+	return &BlastProgressRepository{DB: db}
+}
+
+// Increment atomically counts the outcome of the message of a lead, creating
+// the progress of the blast with its first message. Each lead is counted
+// once: a redelivered outcome is ignored and a different one replaces the
+// outcome counted before. It returns the updated progress and whether this
+// call completed the blast, which is reported to a single caller.
+func (repo *BlastProgressRepository) Increment(ctx context.Context, organizationID, communicationWhatsappID, leadID, expected int, outcome string) (*models.BlastProgress, bool, error) {
+	now := time.Now()
+	progress := &models.BlastProgress{
+		CommunicationWhatsappID: communicationWhatsappID,
+		OrganizationID:          organizationID,
+		Expected:                expected,
+		CreatedAt:               now,
+		UpdatedAt:               now,
+	}
+
+	switch outcome {
+	case models.BlastOutcomeSent:
+		progress.Sent = 1
+	case models.BlastOutcomeFailed:
+		progress.Failed = 1
+	case models.BlastOutcomeCanceled:
+		progress.Canceled = 1
+	default:
+		return nil, false, fmt.Errorf("unknown blast outcome: %s", outcome)
+	}
+
+	var completed bool
+	err := repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		counts := map[string]interface{}{
+			outcome:      gorm.Expr("blast_progress." + outcome + " + 1"),
+			"expected":   gorm.Expr("GREATEST(blast_progress.expected, EXCLUDED.expected)"),
+			"updated_at": now,
+		}
+
+		lead := &models.BlastProgressLead{
+			CommunicationWhatsappID: communicationWhatsappID,
+			LeadID:                  leadID,
+			Outcome:                 outcome,
+			UpdatedAt:               now,
+		}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(lead)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			var counted models.BlastProgressLead
+			result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("communication_whatsapp_id = ? AND lead_id = ?", communicationWhatsappID, leadID).
+				First(&counted)
+			if result.Error != nil {
+				return result.Error
+			}
+			if counted.Outcome == outcome {
+				return nil
+			}
+
+			result = tx.Model(&models.BlastProgressLead{}).
+				Where("communication_whatsapp_id = ? AND lead_id = ?", communicationWhatsappID, leadID).
+				Updates(map[string]interface{}{"outcome": outcome, "updated_at": now})
+			if result.Error != nil {
+				return result.Error
+			}
+			counts[counted.Outcome] = gorm.Expr("blast_progress." + counted.Outcome + " - 1")
+		}
+
+		result = tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "communication_whatsapp_id"}},
+			DoUpdates: clause.Assignments(counts),
+		}).Create(progress)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		// Only the update that sees the last outcome sets completed_at
+		result = tx.Model(&models.BlastProgress{}).
+			Where("communication_whatsapp_id = ? AND completed_at IS NULL AND expected > 0 AND sent + failed + canceled >= expected", communicationWhatsappID).
+			UpdateColumn("completed_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		completed = result.RowsAffected == 1
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	progress, err = repo.FindByCommunication(ctx, communicationWhatsappID)
+	if err != nil {
+		return nil, false, err
+	}
+	return progress, completed, nil
+}
+
+// FindByCommunication returns nil when no message of the blast was processed
+// yet.
+func (repo *BlastProgressRepository) FindByCommunication(ctx context.Context, communicationWhatsappID int) (*models.BlastProgress, error) {
+	var progress models.BlastProgress
+	result := repo.DB.WithContext(ctx).Where("communication_whatsapp_id = ?", communicationWhatsappID).First(&progress)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &progress, nil
+}
@@ -0,0 +1,81 @@
+package repositories
+
+import (
+	"afrus-whatsapp-evolution_api-notification/internal/domain/models"
+	"context"
+	"os"
+	"sync"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TEST_DATABASE_DSN must point at a scratch PostgreSQL database.
+func TestBlastProgressIncrementCountsLeadsOnce(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("error opening database: %v", err)
+	}
+	if err := db.Exec("CREATE SCHEMA IF NOT EXISTS whatsapp").Error; err != nil {
+		t.Fatalf("error creating schema: %v", err)
+	}
+	if err := db.AutoMigrate(&models.BlastProgress{}, &models.BlastProgressLead{}); err != nil {
+		t.Fatalf("error migrating: %v", err)
+	}
+
+	const communicationID, organizationID, expected = 987654321, 1, 3
+	cleanup := func() {
+		db.Where("communication_whatsapp_id = ?", communicationID).Delete(&models.BlastProgressLead{})
+		db.Where("communication_whatsapp_id = ?", communicationID).Delete(&models.BlastProgress{})
+	}
+	cleanup()
+	t.Cleanup(cleanup)
+
+	ctx := context.Background()
+	repo := NewBlastProgressRepository(db)
+
+	// Concurrent redeliveries of the same lead count once
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, err := repo.Increment(ctx, organizationID, communicationID, 1, expected, models.BlastOutcomeFailed); err != nil {
+				t.Errorf("error incrementing: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// A later outcome replaces the counted one
+	progress, completed, err := repo.Increment(ctx, organizationID, communicationID, 1, expected, models.BlastOutcomeSent)
+	if err != nil {
+		t.Fatalf("error incrementing: %v", err)
+	}
+	if progress.Sent != 1 || progress.Failed != 0 || completed {
+		t.Fatalf("got sent %d, failed %d, completed %v, want 1 sent and not completed", progress.Sent, progress.Failed, completed)
+	}
+
+	if _, completed, err = repo.Increment(ctx, organizationID, communicationID, 2, expected, models.BlastOutcomeCanceled); err != nil || completed {
+		t.Fatalf("second lead: completed %v, err %v", completed, err)
+	}
+	progress, completed, err = repo.Increment(ctx, organizationID, communicationID, 3, expected, models.BlastOutcomeSent)
+	if err != nil {
+		t.Fatalf("error incrementing: %v", err)
+	}
+	if !completed || progress.Processed() != expected {
+		t.Fatalf("got %d processed, completed %v, want %d and completed", progress.Processed(), completed, expected)
+	}
+
+	// Redelivering the last lead doesn't complete the blast again
+	if _, completed, err = repo.Increment(ctx, organizationID, communicationID, 3, expected, models.BlastOutcomeSent); err != nil || completed {
+		t.Fatalf("redelivery: completed %v, err %v", completed, err)
+	}
+}
@@ -13,6 +13,7 @@ type CommunicationWhatsappRepository struct {
 
 type CommunicationWhatsappRepositoryInterface interface {
 	FindById(ctx context.Context, id int) (*models.CommunicationWhatsapp, error)
+	CountLeads(ctx context.Context, id int) (int, error)
 }
 
 func NewCommunicationWhatsappRepository(db *gorm.DB) *CommunicationWhatsappRepository {
@@ -30,5 +31,25 @@ func (repo *CommunicationWhatsappRepository) FindById(ctx context.Context, id in
 	if result.Error != nil {
 		return nil, result.Error
 	}
+
+	if err := findContentVariants(repo.DB.WithContext(ctx), &communicationWhatsapp.Contents, "communication_whatsapp_id = ?", id); err != nil {
+		return nil, err
+	}
 	return &communicationWhatsapp, nil
 }
+
+// CountLeads returns the number of leads the communication of the WhatsApp
+// communication id is sent to.
+func (repo *CommunicationWhatsappRepository) CountLeads(ctx context.Context, id int) (int, error) {
+	var count int64
+	result := repo.DB.WithContext(ctx).
+		Model(&models.CommunicationLead{}).
+		Joins("JOIN blasts.communication_whatsapps ON blasts.communication_whatsapps.communication_id = blasts.communication_leads.communication_id").
+		Where("blasts.communication_whatsapps.id = ?", id).
+		Distinct("blasts.communication_leads.lead_id").
+		Count(&count)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return int(count), nil
+}
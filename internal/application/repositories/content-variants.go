@@ -0,0 +1,23 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// undefinedTableCode is the Postgres error code of a missing table.
+const undefinedTableCode = "42P01"
+
+// findContentVariants loads the per-language contents matching query into
+// dest. The variant tables are optional in the Afrus DB: when they don't
+// exist dest stays empty and the default content is sent.
+func findContentVariants(db *gorm.DB, dest interface{}, query string, args ...interface{}) error {
+	err := db.Where(query, args...).Find(dest).Error
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == undefinedTableCode {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,116 @@
+package repositories
+
+import (
+	"afrus-whatsapp-evolution_api-notification/internal/domain/models"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IdempotencyKeyRepository struct {
+	DB *gorm.DB
+}
+
+type IdempotencyKeyRepositoryInterface interface {
+	Find(ctx context.Context, key string) (*models.IdempotencyKey, error)
+	Claim(ctx context.Context, key *models.IdempotencyKey, lease time.Duration) (bool, error)
+	Release(ctx context.Context, key string) error
+	ClearPendingBilling(ctx context.Context, key string) error
+	SaveWithEvent(ctx context.Context, key *models.IdempotencyKey, dbName string, whatsappEvent *models.WhatsappEvent) error
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+func NewIdempotencyKeyRepository(db *gorm.DB) *IdempotencyKeyRepository {
+	return &IdempotencyKeyRepository{DB: db}
+}
+
+// Find returns the unexpired key, or nil when the message wasn't processed
+// and nobody is processing it.
+func (repo *IdempotencyKeyRepository) Find(ctx context.Context, key string) (*models.IdempotencyKey, error) {
+	var idempotencyKey models.IdempotencyKey
+	result := repo.DB.WithContext(ctx).Where("key = ? AND expires_at > ?", key, time.Now()).First(&idempotencyKey)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &idempotencyKey, nil
+}
+
+// Claim inserts key as a processing claim that expires after lease, taking
+// over an expired key. It returns false when another delivery already sent
+// the message or holds an unexpired claim on it.
+func (repo *IdempotencyKeyRepository) Claim(ctx context.Context, key *models.IdempotencyKey, lease time.Duration) (bool, error) {
+	now := time.Now()
+	claim := *key
+	claim.Status = models.IdempotencyStatusProcessing
+	claim.CreatedAt = now
+	claim.ExpiresAt = now.Add(lease)
+
+	result := repo.DB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"status", "message_id", "pending_billing_event", "created_at", "expires_at"}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Expr{SQL: "idempotency_keys.expires_at <= ?", Vars: []interface{}{now}},
+		}},
+	}).Create(&claim)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Release deletes the processing claim on key so a redelivery can send the
+// message. Sent keys are kept.
+func (repo *IdempotencyKeyRepository) Release(ctx context.Context, key string) error {
+	result := repo.DB.WithContext(ctx).
+		Where("key = ? AND status = ?", key, models.IdempotencyStatusProcessing).
+		Delete(&models.IdempotencyKey{})
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+// ClearPendingBilling marks the billing event of the key as published.
+func (repo *IdempotencyKeyRepository) ClearPendingBilling(ctx context.Context, key string) error {
+	result := repo.DB.WithContext(ctx).Model(&models.IdempotencyKey{}).Where("key = ?", key).UpdateColumn("pending_billing_event", "")
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+// SaveWithEvent stores the key and the event in a single transaction so a
+// message is never recorded as sent without also being marked as processed.
+func (repo *IdempotencyKeyRepository) SaveWithEvent(ctx context.Context, key *models.IdempotencyKey, dbName string, whatsappEvent *models.WhatsappEvent) error {
+	key.Status = models.IdempotencyStatusSent
+	return repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "key"}},
+			DoUpdates: clause.AssignmentColumns([]string{"status", "message_id", "pending_billing_event", "created_at", "expires_at"}),
+		}).Create(key)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		tableName := fmt.Sprintf("whatsapp.%s", dbName)
+		if err := tx.Table(tableName).Create(whatsappEvent).Error; err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+func (repo *IdempotencyKeyRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	result := repo.DB.WithContext(ctx).Where("expires_at <= ?", time.Now()).Delete(&models.IdempotencyKey{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
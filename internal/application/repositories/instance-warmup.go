@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"afrus-whatsapp-evolution_api-notification/internal/domain/models"
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type InstanceWarmupRepository struct {
+	DB *gorm.DB
+}
+
+type InstanceWarmupRepositoryInterface interface {
+	Update(ctx context.Context, initial *models.InstanceWarmup, fn func(warmup *models.InstanceWarmup) error) (*models.InstanceWarmup, error)
+	FindByOrganization(ctx context.Context, organizationID int) ([]models.InstanceWarmup, error)
+}
+
+func NewInstanceWarmupRepository(db *gorm.DB) *InstanceWarmupRepository {
+	return &InstanceWarmupRepository{DB: db}
+}
+
+// Update locks the warm-up of the instance, creating it from initial when it
+// doesn't exist yet, and stores the changes fn makes. Nothing is stored when
+// fn fails.
+func (repo *InstanceWarmupRepository) Update(ctx context.Context, initial *models.InstanceWarmup, fn func(warmup *models.InstanceWarmup) error) (*models.InstanceWarmup, error) {
+	var warmup models.InstanceWarmup
+	err := repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(initial).Error; err != nil {
+			return err
+		}
+
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("whatsapp_instance_id = ?", initial.WhatsappInstanceID).First(&warmup)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if err := fn(&warmup); err != nil {
+			return err
+		}
+
+		return tx.Save(&warmup).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &warmup, nil
+}
+
+func (repo *InstanceWarmupRepository) FindByOrganization(ctx context.Context, organizationID int) ([]models.InstanceWarmup, error) {
+	var warmups []models.InstanceWarmup
+	result := repo.DB.WithContext(ctx).Where("organization_id = ?", organizationID).Find(&warmups)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return warmups, nil
+}
@@ -0,0 +1,47 @@
+package repositories
+
+import (
+	"afrus-whatsapp-evolution_api-notification/internal/domain/models"
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LeadSenderRepository struct {
+	DB *gorm.DB
+}
+
+type LeadSenderRepositoryInterface interface {
+	Find(ctx context.Context, organizationID, leadID int) (*models.LeadSender, error)
+	Save(ctx context.Context, leadSender *models.LeadSender) error
+}
+
+func NewLeadSenderRepository(db *gorm.DB) *LeadSenderRepository {
+	return &LeadSenderRepository{DB: db}
+}
+
+// Find returns nil when no instance has messaged the lead yet.
+func (repo *LeadSenderRepository) Find(ctx context.Context, organizationID, leadID int) (*models.LeadSender, error) {
+	var leadSender models.LeadSender
+	result := repo.DB.WithContext(ctx).Where("organization_id = ? AND lead_id = ?", organizationID, leadID).First(&leadSender)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &leadSender, nil
+}
+
+func (repo *LeadSenderRepository) Save(ctx context.Context, leadSender *models.LeadSender) error {
+	result := repo.DB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "organization_id"}, {Name: "lead_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"whatsapp_instance_id", "updated_at"}),
+	}).Create(leadSender)
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
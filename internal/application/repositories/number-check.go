@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"afrus-whatsapp-evolution_api-notification/internal/domain/models"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type NumberCheckRepository struct {
+	DB *gorm.DB
+}
+
+type NumberCheckRepositoryInterface interface {
+	Find(ctx context.Context, phone string) (*models.NumberCheck, error)
+	Save(ctx context.Context, check *models.NumberCheck) error
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+func NewNumberCheckRepository(db *gorm.DB) *NumberCheckRepository {
+	return &NumberCheckRepository{DB: db}
+}
+
+// Find returns the unexpired check for phone, or nil when it has to be
+// checked again.
+func (repo *NumberCheckRepository) Find(ctx context.Context, phone string) (*models.NumberCheck, error) {
+	var check models.NumberCheck
+	result := repo.DB.WithContext(ctx).Where("phone = ? AND expires_at > ?", phone, time.Now()).First(&check)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &check, nil
+}
+
+func (repo *NumberCheckRepository) Save(ctx context.Context, check *models.NumberCheck) error {
+	result := repo.DB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "phone"}},
+		DoUpdates: clause.AssignmentColumns([]string{"exists", "jid", "checked_at", "expires_at"}),
+	}).Create(check)
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+func (repo *NumberCheckRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	result := repo.DB.WithContext(ctx).Where("expires_at <= ?", time.Now()).Delete(&models.NumberCheck{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,35 @@
+package repositories
+
+import (
+	"afrus-whatsapp-evolution_api-notification/internal/domain/models"
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type OrganizationSettingsRepository struct {
+	DB *gorm.DB
+}
+
+type OrganizationSettingsRepositoryInterface interface {
+	FindByOrganization(ctx context.Context, organizationID int) (*models.OrganizationSettings, error)
+}
+
+func NewOrganizationSettingsRepository(db *gorm.DB) *OrganizationSettingsRepository {
+	return &OrganizationSettingsRepository{DB: db}
+}
+
+// FindByOrganization returns zero-valued settings when the organization has
+// none stored, so callers can always fall back to the service defaults.
+func (repo *OrganizationSettingsRepository) FindByOrganization(ctx context.Context, organizationID int) (*models.OrganizationSettings, error) {
+	var settings models.OrganizationSettings
+	result := repo.DB.WithContext(ctx).Where("organization_id = ?", organizationID).First(&settings)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return &models.OrganizationSettings{OrganizationID: organizationID}, nil
+		}
+		return nil, result.Error
+	}
+	return &settings, nil
+}
@@ -0,0 +1,68 @@
+package repositories
+
+import (
+	"afrus-whatsapp-evolution_api-notification/internal/domain/models"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PendingStatusEventRepository struct {
+	DB *gorm.DB
+}
+
+type PendingStatusEventRepositoryInterface interface {
+	Park(ctx context.Context, pending *models.PendingStatusEvent) error
+	Take(ctx context.Context, messageID string, fn func(tx *gorm.DB, pending *models.PendingStatusEvent) error) error
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+func NewPendingStatusEventRepository(db *gorm.DB) *PendingStatusEventRepository {
+	return &PendingStatusEventRepository{DB: db}
+}
+
+// Park stores the status, keeping the first one received when the same
+// status of the message is already parked.
+func (repo *PendingStatusEventRepository) Park(ctx context.Context, pending *models.PendingStatusEvent) error {
+	result := repo.DB.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(pending)
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+// Take locks the statuses parked for the message and deletes them once fn has
+// handled each of them inside the same transaction. Nothing is deleted when fn
+// fails, and statuses locked by a concurrent Take are skipped.
+func (repo *PendingStatusEventRepository) Take(ctx context.Context, messageID string, fn func(tx *gorm.DB, pending *models.PendingStatusEvent) error) error {
+	return repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var parked []models.PendingStatusEvent
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("message_id = ?", messageID).
+			Order("received_at ASC").
+			Find(&parked)
+		if result.Error != nil {
+			return result.Error
+		}
+		if len(parked) == 0 {
+			return nil
+		}
+
+		for i := range parked {
+			if err := fn(tx, &parked[i]); err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&parked).Error
+	})
+}
+
+func (repo *PendingStatusEventRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	result := repo.DB.WithContext(ctx).Where("expires_at <= ?", time.Now()).Delete(&models.PendingStatusEvent{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
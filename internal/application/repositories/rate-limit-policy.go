@@ -0,0 +1,38 @@
+package repositories
+
+import (
+	"afrus-whatsapp-evolution_api-notification/internal/domain/models"
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type RateLimitPolicyRepository struct {
+	DB *gorm.DB
+}
+
+type RateLimitPolicyRepositoryInterface interface {
+	FindForInstance(ctx context.Context, organizationID int, instanceID uint) (*models.RateLimitPolicy, error)
+}
+
+func NewRateLimitPolicyRepository(db *gorm.DB) *RateLimitPolicyRepository {
+	return &RateLimitPolicyRepository{DB: db}
+}
+
+// FindForInstance returns the policy of the instance, falling back to the
+// organization policy, or nil when neither is configured.
+func (repo *RateLimitPolicyRepository) FindForInstance(ctx context.Context, organizationID int, instanceID uint) (*models.RateLimitPolicy, error) {
+	var policy models.RateLimitPolicy
+	result := repo.DB.WithContext(ctx).
+		Where("organization_id = ? AND (whatsapp_instance_id = ? OR whatsapp_instance_id IS NULL)", organizationID, instanceID).
+		Order("whatsapp_instance_id IS NULL").
+		First(&policy)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &policy, nil
+}
@@ -0,0 +1,59 @@
+package repositories
+
+import (
+	"afrus-whatsapp-evolution_api-notification/internal/domain/models"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type ShortLinkRepository struct {
+	DB *gorm.DB
+}
+
+type ShortLinkRepositoryInterface interface {
+	Create(ctx context.Context, shortLink *models.ShortLink) error
+	FindByCode(ctx context.Context, code string) (*models.ShortLink, error)
+	AttachMessageID(ctx context.Context, codes []string, messageID string) error
+	SaveClick(ctx context.Context, click *models.LinkClick) error
+}
+
+func NewShortLinkRepository(db *gorm.DB) *ShortLinkRepository {
+	return &ShortLinkRepository{DB: db}
+}
+
+func (repo *ShortLinkRepository) Create(ctx context.Context, shortLink *models.ShortLink) error {
+	result := repo.DB.WithContext(ctx).Create(shortLink)
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+func (repo *ShortLinkRepository) FindByCode(ctx context.Context, code string) (*models.ShortLink, error) {
+	var shortLink models.ShortLink
+	result := repo.DB.WithContext(ctx).Where("code = ?", code).First(&shortLink)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &shortLink, nil
+}
+
+func (repo *ShortLinkRepository) AttachMessageID(ctx context.Context, codes []string, messageID string) error {
+	if len(codes) == 0 {
+		return nil
+	}
+	result := repo.DB.WithContext(ctx).Model(&models.ShortLink{}).Where("code IN ?", codes).Update("message_id", messageID)
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+func (repo *ShortLinkRepository) SaveClick(ctx context.Context, click *models.LinkClick) error {
+	result := repo.DB.WithContext(ctx).Create(click)
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
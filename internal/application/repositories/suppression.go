@@ -0,0 +1,88 @@
+package repositories
+
+import (
+	"afrus-whatsapp-evolution_api-notification/internal/domain/models"
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SuppressionRepository struct {
+	DB *gorm.DB
+}
+
+type SuppressionRepositoryInterface interface {
+	Create(ctx context.Context, suppression *models.Suppression) error
+	Delete(ctx context.Context, organizationID, id int) error
+	FindByOrganization(ctx context.Context, organizationID int) ([]models.Suppression, error)
+	FindByPhone(ctx context.Context, organizationID int, phone string) ([]models.Suppression, error)
+	FindMatch(ctx context.Context, organizationID, leadID int, phone string) (*models.Suppression, error)
+}
+
+func NewSuppressionRepository(db *gorm.DB) *SuppressionRepository {
+	return &SuppressionRepository{DB: db}
+}
+
+// Create returns gorm.ErrDuplicatedKey when the organization already has an
+// entry for the same lead, phone or for itself.
+func (repo *SuppressionRepository) Create(ctx context.Context, suppression *models.Suppression) error {
+	result := repo.DB.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(suppression)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrDuplicatedKey
+	}
+	return nil
+}
+
+func (repo *SuppressionRepository) Delete(ctx context.Context, organizationID, id int) error {
+	result := repo.DB.WithContext(ctx).Where("organization_id = ? AND id = ?", organizationID, id).Delete(&models.Suppression{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (repo *SuppressionRepository) FindByOrganization(ctx context.Context, organizationID int) ([]models.Suppression, error) {
+	var suppressions []models.Suppression
+	result := repo.DB.WithContext(ctx).Where("organization_id = ?", organizationID).Order("created_at DESC").Find(&suppressions)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return suppressions, nil
+}
+
+func (repo *SuppressionRepository) FindByPhone(ctx context.Context, organizationID int, phone string) ([]models.Suppression, error) {
+	var suppressions []models.Suppression
+	result := repo.DB.WithContext(ctx).Where("organization_id = ? AND phone = ?", organizationID, phone).Order("created_at DESC").Find(&suppressions)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return suppressions, nil
+}
+
+// FindMatch returns the entry suppressing the lead or phone within the
+// organization, or nil when sends are allowed.
+func (repo *SuppressionRepository) FindMatch(ctx context.Context, organizationID, leadID int, phone string) (*models.Suppression, error) {
+	var suppression models.Suppression
+	result := repo.DB.WithContext(ctx).
+		Where("organization_id = ?", organizationID).
+		Where(repo.DB.
+			Where("lead_id IS NULL AND phone IS NULL").
+			Or("lead_id = ?", leadID).
+			Or("phone = ?", phone)).
+		First(&suppression)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &suppression, nil
+}
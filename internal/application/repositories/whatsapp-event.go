@@ -13,7 +13,9 @@ type WhatsappEventRepository struct {
 }
 
 type WhatsappEventRepositoryInterface interface {
-	Save(dbName string, whatsappEvent models.WhatsappEvent) (int, error)
+	Save(ctx context.Context, dbName string, whatsappEvent *models.WhatsappEvent) error
+	FindByMessageID(ctx context.Context, dbName string, messageID string) (*models.WhatsappEvent, error)
+	ExistsByMessageID(ctx context.Context, dbName string, messageID string) (bool, error)
 }
 
 func NewWhatsappEventRepository(db *gorm.DB) *WhatsappEventRepository {
@@ -28,3 +30,23 @@ func (repo *WhatsappEventRepository) Save(ctx context.Context, dbName string, wh
 	}
 	return nil
 }
+
+func (repo *WhatsappEventRepository) FindByMessageID(ctx context.Context, dbName string, messageID string) (*models.WhatsappEvent, error) {
+	var whatsappEvent models.WhatsappEvent
+	tableName := fmt.Sprintf("whatsapp.%s", dbName)
+	result := repo.DB.WithContext(ctx).Table(tableName).Where("message_id = ?", messageID).Order("id DESC").First(&whatsappEvent)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &whatsappEvent, nil
+}
+
+func (repo *WhatsappEventRepository) ExistsByMessageID(ctx context.Context, dbName string, messageID string) (bool, error) {
+	var count int64
+	tableName := fmt.Sprintf("whatsapp.%s", dbName)
+	result := repo.DB.WithContext(ctx).Table(tableName).Where("message_id = ?", messageID).Count(&count)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return count > 0, nil
+}
@@ -4,8 +4,10 @@ import (
 	"afrus-whatsapp-evolution_api-notification/internal/domain/models"
 	"context"
 	"fmt"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type WhatsappInstanceRepository struct {
@@ -15,7 +17,12 @@ type WhatsappInstanceRepository struct {
 type WhatsappInstanceRepositoryInterface interface {
 	Update(ctx context.Context, instance *models.WhatsappInstance) error
 	GetWhatsappInstanceById(ctx context.Context, id int) (*models.WhatsappInstance, error)
+	GetWhatsappInstanceByName(ctx context.Context, name string) (*models.WhatsappInstance, error)
 	GetWhatsappInstancesByOrganization(ctx context.Context, whatsappInstance *models.WhatsappInstance) ([]models.WhatsappInstance, error)
+	GetWhatsappInstances(ctx context.Context) ([]models.WhatsappInstance, error)
+	GetWhatsappInstancesByOrganizationID(ctx context.Context, organizationID int) ([]models.WhatsappInstance, error)
+	UpdateConnectionState(ctx context.Context, id uint, state string, checkedAt time.Time) error
+	ReserveSend(ctx context.Context, id uint, rules func(instance *models.WhatsappInstance) error) (*models.WhatsappInstance, error)
 }
 
 func NewWhatsappInstanceRepository(db *gorm.DB) *WhatsappInstanceRepository {
@@ -41,6 +48,15 @@ func (repo *WhatsappInstanceRepository) GetWhatsappInstanceById(ctx context.Cont
 	return &instance, nil
 }
 
+func (repo *WhatsappInstanceRepository) GetWhatsappInstanceByName(ctx context.Context, name string) (*models.WhatsappInstance, error) {
+	var instance models.WhatsappInstance
+	result := repo.db.WithContext(ctx).Where(`"instanceName" = ?`, name).First(&instance)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &instance, nil
+}
+
 func (repo *WhatsappInstanceRepository) GetWhatsappInstancesByOrganization(ctx context.Context, whatsappInstance *models.WhatsappInstance) ([]models.WhatsappInstance, error) {
 	var instances []models.WhatsappInstance
 	result := repo.db.WithContext(ctx).Where("organization_id = ? AND id != ?", whatsappInstance.OrganizationID, whatsappInstance.ID).Order("created_at ASC").Find(&instances)
@@ -51,3 +67,69 @@ func (repo *WhatsappInstanceRepository) GetWhatsappInstancesByOrganization(ctx c
 	fmt.Printf("Whatsapp instances: %v\n", instances)
 	return instances, nil
 }
+
+func (repo *WhatsappInstanceRepository) GetWhatsappInstances(ctx context.Context) ([]models.WhatsappInstance, error) {
+	var instances []models.WhatsappInstance
+	result := repo.db.WithContext(ctx).Order("id ASC").Find(&instances)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return instances, nil
+}
+
+func (repo *WhatsappInstanceRepository) GetWhatsappInstancesByOrganizationID(ctx context.Context, organizationID int) ([]models.WhatsappInstance, error) {
+	var instances []models.WhatsappInstance
+	result := repo.db.WithContext(ctx).Where("organization_id = ?", organizationID).Order("created_at ASC").Find(&instances)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return instances, nil
+}
+
+// UpdateConnectionState merges the connection state into the instance data
+// in a single statement, so it doesn't overwrite keys written concurrently
+// by the senders. last_seen only moves forward while the instance is open.
+func (repo *WhatsappInstanceRepository) UpdateConnectionState(ctx context.Context, id uint, state string, checkedAt time.Time) error {
+	fields := `'connection_state', ?::text, 'connection_checked_at', ?::text`
+	args := []interface{}{state, checkedAt.Format(time.RFC3339)}
+	if state == models.ConnectionStateOpen {
+		fields += `, 'last_seen', ?::text`
+		args = append(args, checkedAt.Format(time.RFC3339))
+	}
+
+	result := repo.db.WithContext(ctx).
+		Model(&models.WhatsappInstance{}).
+		Where("id = ?", id).
+		UpdateColumn("data", gorm.Expr("COALESCE(data, '{}'::jsonb) || jsonb_build_object("+fields+")", args...))
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+// ReserveSend locks the instance row, lets rules check and update its data
+// and stores the result in the same transaction, so concurrent senders of
+// the same instance see each other's sends. Nothing is stored when rules
+// fail.
+func (repo *WhatsappInstanceRepository) ReserveSend(ctx context.Context, id uint, rules func(instance *models.WhatsappInstance) error) (*models.WhatsappInstance, error) {
+	var instance models.WhatsappInstance
+	err := repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&instance)
+		if result.Error != nil {
+			return result.Error
+		}
+		if instance.Data == nil {
+			instance.Data = models.JSONB{}
+		}
+
+		if err := rules(&instance); err != nil {
+			return err
+		}
+
+		return tx.Model(&models.WhatsappInstance{}).Where("id = ?", id).UpdateColumn("data", instance.Data).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &instance, nil
+}
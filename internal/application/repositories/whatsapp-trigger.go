@@ -27,5 +27,9 @@ func (repo *WhatsappTriggerRepository) GetWhatsappTriggerById(ctx context.Contex
 	if result.Error != nil {
 		return nil, result.Error
 	}
+
+	if err := findContentVariants(repo.db.WithContext(ctx), &instance.Contents, "whatsapp_trigger_id = ?", id); err != nil {
+		return nil, err
+	}
 	return &instance, nil
 }
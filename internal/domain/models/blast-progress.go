@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// Outcomes counted by BlastProgress, named after the event stored for them.
+const (
+	BlastOutcomeSent     = "sent"
+	BlastOutcomeFailed   = "failed"
+	BlastOutcomeCanceled = "canceled"
+)
+
+// BlastProgress aggregates the outcome of the messages of a blast. Expected
+// is the number of leads the blast was sent to, as announced by the events or
+// else counted from the leads of its communication.
+type BlastProgress struct {
+	CommunicationWhatsappID int        `json:"communicationWhatsappId" gorm:"column:communication_whatsapp_id;type:int;primaryKey;autoIncrement:false"`
+	OrganizationID          int        `json:"organizationId" gorm:"column:organization_id;type:int;index"`
+	Expected                int        `json:"expected" gorm:"column:expected;type:int"`
+	Sent                    int        `json:"sent" gorm:"column:sent;type:int"`
+	Failed                  int        `json:"failed" gorm:"column:failed;type:int"`
+	Canceled                int        `json:"canceled" gorm:"column:canceled;type:int"`
+	CompletedAt             *time.Time `json:"completedAt" gorm:"column:completed_at;type:timestamp"`
+	CreatedAt               time.Time  `json:"createdAt" gorm:"column:created_at;type:timestamp"`
+	UpdatedAt               time.Time  `json:"updatedAt" gorm:"column:updated_at;type:timestamp"`
+}
+
+func (BlastProgress) TableName() string {
+	return "whatsapp.blast_progress"
+}
+
+// Processed returns the messages that reached a final outcome.
+func (p *BlastProgress) Processed() int {
+	return p.Sent + p.Failed + p.Canceled
+}
+
+// Pending returns the messages still to be processed. It is zero when the
+// expected count is unknown.
+func (p *BlastProgress) Pending() int {
+	return max(p.Expected-p.Processed(), 0)
+}
+
+// Finished reports whether every expected message reached a final outcome.
+func (p *BlastProgress) Finished() bool {
+	return p.Expected > 0 && p.Processed() >= p.Expected
+}
+
+// BlastProgressLead is the outcome counted for a lead of a blast, so a
+// redelivered message doesn't count the lead twice.
+type BlastProgressLead struct {
+	CommunicationWhatsappID int       `json:"communicationWhatsappId" gorm:"column:communication_whatsapp_id;type:int;primaryKey;autoIncrement:false"`
+	LeadID                  int       `json:"leadId" gorm:"column:lead_id;type:int;primaryKey;autoIncrement:false"`
+	Outcome                 string    `json:"outcome" gorm:"column:outcome;type:varchar(20)"`
+	UpdatedAt               time.Time `json:"updatedAt" gorm:"column:updated_at;type:timestamp"`
+}
+
+func (BlastProgressLead) TableName() string {
+	return "whatsapp.blast_progress_leads"
+}
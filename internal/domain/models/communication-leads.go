@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// CommunicationLead is a lead a communication is sent to.
+type CommunicationLead struct {
+	ID              int       `json:"id" gorm:"column:id;type:int"`
+	CommunicationID int       `json:"communicationId" gorm:"column:communication_id;type:int"`
+	LeadID          int       `json:"leadId" gorm:"column:lead_id;type:int"`
+	CreatedAt       time.Time `json:"createdAt" gorm:"column:created_at;type:timestamp"`
+}
+
+func (CommunicationLead) TableName() string {
+	return "blasts.communication_leads"
+}
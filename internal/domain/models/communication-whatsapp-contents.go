@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+type CommunicationWhatsappContent struct {
+	ID                      int       `json:"id" gorm:"column:id;type:int"`
+	CommunicationWhatsappID int       `json:"communicationWhatsappId" gorm:"column:communication_whatsapp_id;type:int"`
+	LanguageCode            string    `json:"languageCode" gorm:"column:language_code;type:varchar(255)"`
+	Content                 string    `json:"content" gorm:"column:content;type:text"`
+	CreatedAt               time.Time `json:"createdAt" gorm:"column:created_at;type:timestamp"`
+	UpdatedAt               time.Time `json:"updatedAt" gorm:"column:updated_at;type:timestamp"`
+}
+
+func (CommunicationWhatsappContent) TableName() string {
+	return "blasts.communication_whatsapp_contents"
+}
@@ -11,10 +11,34 @@ type CommunicationWhatsapp struct {
 	CommunicationID int                               `json:"communicationId" gorm:"column:communication_id;type:int"`
 	OrganizationID  int                               `json:"organizationId" gorm:"column:organization_id;type:int"`
 	Content         string                            `json:"content" gorm:"column:content;type:text"`
+	GASource        *string                           `json:"gaSource" gorm:"column:ga_source;type:varchar(255)"`
+	GAMedium        *string                           `json:"gaMedium" gorm:"column:ga_medium;type:varchar(255)"`
+	GAName          *string                           `json:"gaName" gorm:"column:ga_name;type:varchar(255)"`
+	GAContent       *string                           `json:"gaContent" gorm:"column:ga_content;type:varchar(255)"`
 	Instances       []CommunicationWhatsappInstance   `gorm:"foreignKey:CommunicationWhatsappID" json:"instances"`
 	Attachments     []CommunicationWhatsappAttachment `gorm:"foreignKey:CommunicationWhatsappID" json:"attachments"`
+	Contents        []CommunicationWhatsappContent    `gorm:"foreignKey:CommunicationWhatsappID" json:"contents"`
+}
+
+// ContentVariants returns the per-language contents keyed by language code.
+func (c *CommunicationWhatsapp) ContentVariants() map[string]string {
+	variants := make(map[string]string, len(c.Contents))
+	for _, content := range c.Contents {
+		variants[content.LanguageCode] = content.Content
+	}
+	return variants
 }
 
 func (CommunicationWhatsapp) TableName() string {
 	return "blasts.communication_whatsapps"
 }
+
+// WhatsappInstances returns the instances assigned to the communication in
+// their stored order.
+func (c *CommunicationWhatsapp) WhatsappInstances() []WhatsappInstance {
+	instances := make([]WhatsappInstance, 0, len(c.Instances))
+	for _, instance := range c.Instances {
+		instances = append(instances, instance.WhatsappInstance)
+	}
+	return instances
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Idempotency key statuses. A processing key is a claim held while the
+// message is being sent and expires after a short lease.
+const (
+	IdempotencyStatusProcessing = "processing"
+	IdempotencyStatusSent       = "sent"
+)
+
+type IdempotencyKey struct {
+	Key            string `json:"key" gorm:"column:key;type:varchar(255);primaryKey"`
+	OrganizationID int    `json:"organizationId" gorm:"column:organization_id;type:int"`
+	LeadID         int    `json:"leadId" gorm:"column:lead_id;type:int"`
+	ExternalID     string `json:"externalId" gorm:"column:external_id;type:varchar(255)"`
+	ExternalTable  string `json:"externalTable" gorm:"column:external_table;type:varchar(255)"`
+	MessageID      string `json:"messageId" gorm:"column:message_id;type:varchar(255)"`
+	Status         string `json:"status" gorm:"column:status;type:varchar(20);not null;default:'sent'"`
+	// PendingBillingEvent holds the billing event of the sent message until
+	// it is published, so a retry after a failed publish still bills it.
+	PendingBillingEvent string    `json:"pendingBillingEvent" gorm:"column:pending_billing_event;type:text"`
+	CreatedAt           time.Time `json:"createdAt" gorm:"column:created_at;type:timestamp"`
+	ExpiresAt           time.Time `json:"expiresAt" gorm:"column:expires_at;type:timestamp;index"`
+}
+
+func (IdempotencyKey) TableName() string {
+	return "whatsapp.idempotency_keys"
+}
+
+func NewIdempotencyKey(organizationID, leadID int, externalTable, externalID string, ttl time.Duration) *IdempotencyKey {
+	now := time.Now()
+	return &IdempotencyKey{
+		Key:            fmt.Sprintf("%d:%d:%s:%s", organizationID, leadID, externalTable, externalID),
+		OrganizationID: organizationID,
+		LeadID:         leadID,
+		ExternalID:     externalID,
+		ExternalTable:  externalTable,
+		CreatedAt:      now,
+		ExpiresAt:      now.Add(ttl),
+	}
+}
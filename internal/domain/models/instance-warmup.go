@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// InstanceWarmup tracks the warm-up progress of an instance: its level in
+// the organization warm-up schedule and its sends on the current day.
+type InstanceWarmup struct {
+	WhatsappInstanceID uint       `json:"whatsappInstanceId" gorm:"column:whatsapp_instance_id;type:int;primaryKey;autoIncrement:false"`
+	OrganizationID     int        `json:"organizationId" gorm:"column:organization_id;type:int;index"`
+	Level              int        `json:"level" gorm:"column:level;type:int"`
+	Day                string     `json:"day" gorm:"column:day;type:varchar(10)"`
+	SentToday          int        `json:"sentToday" gorm:"column:sent_today;type:int"`
+	FailedToday        int        `json:"failedToday" gorm:"column:failed_today;type:int"`
+	StartedAt          time.Time  `json:"startedAt" gorm:"column:started_at;type:timestamp"`
+	DemotedAt          *time.Time `json:"demotedAt" gorm:"column:demoted_at;type:timestamp"`
+	DemotionReason     string     `json:"demotionReason" gorm:"column:demotion_reason;type:text"`
+	UpdatedAt          time.Time  `json:"updatedAt" gorm:"column:updated_at;type:timestamp"`
+}
+
+func (InstanceWarmup) TableName() string {
+	return "whatsapp.instance_warmups"
+}
+
+// DemotedOn reports whether the instance was demoted on day (YYYY-MM-DD).
+func (w *InstanceWarmup) DemotedOn(day string) bool {
+	return w.DemotedAt != nil && w.DemotedAt.UTC().Format(time.DateOnly) == day
+}
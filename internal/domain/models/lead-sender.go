@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// LeadSender remembers the instance that last messaged a lead, so later
+// messages keep the conversation on the same number.
+type LeadSender struct {
+	OrganizationID     int       `json:"organizationId" gorm:"column:organization_id;type:int;primaryKey;autoIncrement:false"`
+	LeadID             int       `json:"leadId" gorm:"column:lead_id;type:int;primaryKey;autoIncrement:false"`
+	WhatsappInstanceID uint      `json:"whatsappInstanceId" gorm:"column:whatsapp_instance_id;type:int"`
+	UpdatedAt          time.Time `json:"updatedAt" gorm:"column:updated_at;type:timestamp"`
+}
+
+func (LeadSender) TableName() string {
+	return "whatsapp.lead_senders"
+}
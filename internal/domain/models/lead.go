@@ -1,13 +1,40 @@
 package models
 
+import "fmt"
+
 type Lead struct {
 	ID             int    `json:"id" gorm:"column:id;type:int"`
 	OrganizationID int    `json:"organizationId" gorm:"column:organization_id;type:int"`
 	Email          string `json:"email" gorm:"column:email;type:varchar(255)"`
 	Phone          string `json:"phone" gorm:"column:phone;type:varchar(255)"`
 	LanguageCode   string `json:"languageCode" gorm:"column:language_code;type:varchar(255)"`
+	FirstName      string `json:"firstName" gorm:"column:first_name;type:varchar(255)"`
+	LastName       string `json:"lastName" gorm:"column:last_name;type:varchar(255)"`
+	CustomFields   JSONB  `json:"customFields" gorm:"column:custom_fields;type:jsonb"`
+	Timezone       string `json:"timezone" gorm:"column:timezone;type:varchar(255)"`
 }
 
 func (Lead) TableName() string {
 	return "leads"
 }
+
+// TemplateVariables exposes the lead to message templates as lead.<field>,
+// with custom fields under lead.custom.<name>.
+func (l *Lead) TemplateVariables() map[string]string {
+	vars := map[string]string{
+		"lead.email":         l.Email,
+		"lead.phone":         l.Phone,
+		"lead.first_name":    l.FirstName,
+		"lead.last_name":     l.LastName,
+		"lead.language_code": l.LanguageCode,
+	}
+
+	for name, value := range l.CustomFields {
+		if value == nil {
+			continue
+		}
+		vars["lead.custom."+name] = fmt.Sprint(value)
+	}
+
+	return vars
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// NumberCheck caches whether a phone number has a WhatsApp account, as
+// reported by Evolution API.
+type NumberCheck struct {
+	Phone     string    `json:"phone" gorm:"column:phone;type:varchar(32);primaryKey"`
+	Exists    bool      `json:"exists" gorm:"column:exists"`
+	Jid       string    `json:"jid" gorm:"column:jid;type:varchar(255)"`
+	CheckedAt time.Time `json:"checkedAt" gorm:"column:checked_at;type:timestamp"`
+	ExpiresAt time.Time `json:"expiresAt" gorm:"column:expires_at;type:timestamp;index"`
+}
+
+func (NumberCheck) TableName() string {
+	return "whatsapp.number_checks"
+}
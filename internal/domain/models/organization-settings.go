@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// OrganizationSettings holds the per-organization WhatsApp sending options
+// owned by this service. Organizations without a row use the defaults.
+type OrganizationSettings struct {
+	OrganizationID            int       `json:"organizationId" gorm:"column:organization_id;type:int;primaryKey;autoIncrement:false"`
+	OptOutKeywords            string    `json:"optOutKeywords" gorm:"column:opt_out_keywords;type:text"`
+	OptOutConfirmation        bool      `json:"optOutConfirmation" gorm:"column:opt_out_confirmation"`
+	OptOutConfirmationMessage string    `json:"optOutConfirmationMessage" gorm:"column:opt_out_confirmation_message;type:text"`
+	SendWindowStart           string    `json:"sendWindowStart" gorm:"column:send_window_start;type:varchar(5)"`
+	SendWindowEnd             string    `json:"sendWindowEnd" gorm:"column:send_window_end;type:varchar(5)"`
+	Timezone                  string    `json:"timezone" gorm:"column:timezone;type:varchar(64)"`
+	DefaultCountry            string    `json:"defaultCountry" gorm:"column:default_country;type:varchar(2)"`
+	InstanceSelection         string    `json:"instanceSelection" gorm:"column:instance_selection;type:varchar(32)"`
+	WarmupSchedule            string    `json:"warmupSchedule" gorm:"column:warmup_schedule;type:text"`
+	CreatedAt                 time.Time `json:"createdAt" gorm:"column:created_at;type:timestamp"`
+	UpdatedAt                 time.Time `json:"updatedAt" gorm:"column:updated_at;type:timestamp"`
+}
+
+func (OrganizationSettings) TableName() string {
+	return "whatsapp.organization_settings"
+}
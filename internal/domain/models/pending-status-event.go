@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// PendingStatusEvent parks a status webhook that arrived before the sent
+// event of its message was stored, so it can be recorded once it is.
+type PendingStatusEvent struct {
+	ID         int       `json:"id" gorm:"column:id;primaryKey"`
+	MessageID  string    `json:"messageId" gorm:"column:message_id;type:varchar(255);uniqueIndex:idx_pending_status_events_message_kind"`
+	Kind       string    `json:"kind" gorm:"column:kind;type:varchar(50);uniqueIndex:idx_pending_status_events_message_kind"`
+	Event      JSONB     `json:"event" gorm:"column:event;type:jsonb"`
+	ReceivedAt time.Time `json:"receivedAt" gorm:"column:received_at;type:timestamp"`
+	ExpiresAt  time.Time `json:"expiresAt" gorm:"column:expires_at;type:timestamp;index"`
+}
+
+func (PendingStatusEvent) TableName() string {
+	return "whatsapp.pending_status_events"
+}
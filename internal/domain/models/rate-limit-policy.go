@@ -0,0 +1,36 @@
+package models
+
+import (
+	"afrus-whatsapp-evolution_api-notification/pkg/ratelimit"
+	"time"
+)
+
+// RateLimitPolicy configures how fast instances may send. A row with a
+// WhatsappInstanceID applies to that instance and one without it to every
+// instance of the organization.
+type RateLimitPolicy struct {
+	ID                 int       `json:"id" gorm:"column:id;primaryKey"`
+	OrganizationID     int       `json:"organizationId" gorm:"column:organization_id;type:int;index"`
+	WhatsappInstanceID *uint     `json:"whatsappInstanceId" gorm:"column:whatsapp_instance_id;type:int"`
+	BucketCapacity     int       `json:"bucketCapacity" gorm:"column:bucket_capacity;type:int"`
+	RefillSeconds      int       `json:"refillSeconds" gorm:"column:refill_seconds;type:int"`
+	PerMinute          int       `json:"perMinute" gorm:"column:per_minute;type:int"`
+	PerHour            int       `json:"perHour" gorm:"column:per_hour;type:int"`
+	PerDay             int       `json:"perDay" gorm:"column:per_day;type:int"`
+	CreatedAt          time.Time `json:"createdAt" gorm:"column:created_at;type:timestamp"`
+	UpdatedAt          time.Time `json:"updatedAt" gorm:"column:updated_at;type:timestamp"`
+}
+
+func (RateLimitPolicy) TableName() string {
+	return "whatsapp.rate_limit_policies"
+}
+
+func (p *RateLimitPolicy) Policy() ratelimit.Policy {
+	return ratelimit.Policy{
+		BucketCapacity: p.BucketCapacity,
+		RefillInterval: time.Duration(p.RefillSeconds) * time.Second,
+		PerMinute:      p.PerMinute,
+		PerHour:        p.PerHour,
+		PerDay:         p.PerDay,
+	}
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+type ShortLink struct {
+	ID             int       `json:"id" gorm:"column:id;primaryKey"`
+	Code           string    `json:"code" gorm:"column:code;type:varchar(32);uniqueIndex"`
+	URL            string    `json:"url" gorm:"column:url;type:text"`
+	OrganizationID int       `json:"organizationId" gorm:"column:organization_id;type:int"`
+	LeadID         int       `json:"leadId" gorm:"column:lead_id;type:int"`
+	ExternalID     string    `json:"externalId" gorm:"column:external_id;type:varchar(255)"`
+	ExternalTable  string    `json:"externalTable" gorm:"column:external_table;type:varchar(255)"`
+	MessageID      string    `json:"messageId" gorm:"column:message_id;type:varchar(255);index"`
+	CreatedAt      time.Time `json:"createdAt" gorm:"column:created_at;type:timestamp"`
+}
+
+func (ShortLink) TableName() string {
+	return "whatsapp.short_links"
+}
+
+type LinkClick struct {
+	ID             int       `json:"id" gorm:"column:id;primaryKey"`
+	ShortLinkID    int       `json:"shortLinkId" gorm:"column:short_link_id;type:int;index"`
+	OrganizationID int       `json:"organizationId" gorm:"column:organization_id;type:int"`
+	LeadID         int       `json:"leadId" gorm:"column:lead_id;type:int"`
+	ExternalID     string    `json:"externalId" gorm:"column:external_id;type:varchar(255)"`
+	ExternalTable  string    `json:"externalTable" gorm:"column:external_table;type:varchar(255)"`
+	MessageID      string    `json:"messageId" gorm:"column:message_id;type:varchar(255)"`
+	UserAgent      string    `json:"userAgent" gorm:"column:user_agent;type:text"`
+	IPAddress      string    `json:"ipAddress" gorm:"column:ip_address;type:varchar(64)"`
+	ClickedAt      time.Time `json:"clickedAt" gorm:"column:clicked_at;type:timestamp"`
+}
+
+func (LinkClick) TableName() string {
+	return "whatsapp.link_clicks"
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+const (
+	SuppressionSourceAPI     = "api"
+	SuppressionSourceKeyword = "keyword"
+)
+
+// Suppression blocks WhatsApp sends for an organization. An entry with a
+// LeadID blocks that lead, one with a Phone blocks that number and one with
+// neither blocks the whole organization. An organization holds at most one
+// entry per lead, phone or for itself.
+type Suppression struct {
+	ID             int       `json:"id" gorm:"column:id;primaryKey"`
+	OrganizationID int       `json:"organizationId" gorm:"column:organization_id;type:int;index;uniqueIndex:idx_suppressions_target,priority:1"`
+	LeadID         *int      `json:"leadId" gorm:"column:lead_id;type:int;uniqueIndex:idx_suppressions_target,priority:2,expression:COALESCE(lead_id\\, 0)"`
+	Phone          *string   `json:"phone" gorm:"column:phone;type:varchar(255);uniqueIndex:idx_suppressions_target,priority:3,expression:COALESCE(phone\\, '')"`
+	Reason         string    `json:"reason" gorm:"column:reason;type:text"`
+	Source         string    `json:"source" gorm:"column:source;type:varchar(64)"`
+	CreatedAt      time.Time `json:"createdAt" gorm:"column:created_at;type:timestamp"`
+}
+
+func (Suppression) TableName() string {
+	return "whatsapp.suppressions"
+}
+
+// Scope describes what the entry suppresses, for logs and canceled events.
+func (s *Suppression) Scope() string {
+	switch {
+	case s.LeadID != nil:
+		return "lead"
+	case s.Phone != nil:
+		return "phone"
+	default:
+		return "organization"
+	}
+}
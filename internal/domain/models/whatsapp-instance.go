@@ -43,3 +43,35 @@ type WhatsappInstance struct {
 func (WhatsappInstance) TableName() string {
 	return "whatsapp_instances"
 }
+
+// Connection states reported by Evolution API.
+const (
+	ConnectionStateOpen       = "open"
+	ConnectionStateConnecting = "connecting"
+	ConnectionStateClose      = "close"
+)
+
+// ConnectionState returns the state stored by the health monitor, or an
+// empty string when the instance has not been checked yet.
+func (w *WhatsappInstance) ConnectionState() string {
+	state, _ := w.Data["connection_state"].(string)
+	return state
+}
+
+// IsConnected reports whether messages can be routed through the instance.
+// Instances that have not been checked yet are assumed to be connected.
+func (w *WhatsappInstance) IsConnected() bool {
+	state := w.ConnectionState()
+	return state == "" || state == ConnectionStateOpen
+}
+
+// LastSendTime returns when the instance last sent a message, or the zero
+// time when it never did.
+func (w *WhatsappInstance) LastSendTime() time.Time {
+	lastSendStr, _ := w.Data["last_send_time"].(string)
+	lastSendTime, err := time.Parse(time.RFC3339, lastSendStr)
+	if err != nil {
+		return time.Time{}
+	}
+	return lastSendTime
+}
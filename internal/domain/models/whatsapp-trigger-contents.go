@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+type WhatsappTriggerContent struct {
+	ID                uint      `gorm:"primaryKey" json:"id"`
+	WhatsappTriggerID uint      `gorm:"column:whatsapp_trigger_id" json:"whatsapp_trigger_id"`
+	LanguageCode      string    `gorm:"column:language_code" json:"language_code"`
+	Content           string    `gorm:"column:content" json:"content"`
+	CreatedAt         time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt         time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+}
+
+func (WhatsappTriggerContent) TableName() string {
+	return "autoresponders.whatsapp_trigger_contents"
+}
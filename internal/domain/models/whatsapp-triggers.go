@@ -27,6 +27,17 @@ type WhatsappTrigger struct {
 	CreatedAt       time.Time      `gorm:"column:created_at" json:"created_at"`
 	UpdatedAt       time.Time      `gorm:"column:updated_at" json:"updated_at"`
 	DeletedAt       gorm.DeletedAt `gorm:"column:deleted_at" json:"deleted_at"`
+
+	Contents []WhatsappTriggerContent `gorm:"foreignKey:WhatsappTriggerID" json:"contents"`
+}
+
+// ContentVariants returns the per-language contents keyed by language code.
+func (t *WhatsappTrigger) ContentVariants() map[string]string {
+	variants := make(map[string]string, len(t.Contents))
+	for _, content := range t.Contents {
+		variants[content.LanguageCode] = content.Content
+	}
+	return variants
 }
 
 func (WhatsappTrigger) TableName() string {
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+)
+
+const apiKeyHeader = "X-API-Key"
+
+// requireAPIKey protects management endpoints with SERVER_API_KEY. They stay
+// closed while no key is configured.
+func (s *Server) requireAPIKey(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.Configs.ServerAPIKey == "" {
+			writeError(w, http.StatusForbidden, "api key not configured")
+			return
+		}
+
+		provided := r.Header.Get(apiKeyHeader)
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(s.Configs.ServerAPIKey)) != 1 {
+			writeError(w, http.StatusUnauthorized, "invalid api key")
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+const webhookSecretHeader = "X-Webhook-Secret"
+
+// requireWebhookSecret authenticates Evolution webhooks with
+// EVOLUTION_WEBHOOK_SECRET, sent either in the X-Webhook-Secret header or as
+// the first path segment after /webhook/evolution, for Evolution versions that
+// can't send custom headers. With webhooks split by event, Evolution appends
+// the event name after it. They stay closed while no secret is configured.
+func (s *Server) requireWebhookSecret(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		secret := []byte(s.Configs.EvolutionWebhookSecret)
+		if len(secret) == 0 {
+			writeError(w, http.StatusForbidden, "webhook secret not configured")
+			return
+		}
+
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get(webhookSecretHeader)), secret) != 1 &&
+			subtle.ConstantTimeCompare([]byte(r.PathValue("token")), secret) != 1 {
+			writeError(w, http.StatusUnauthorized, "invalid webhook secret")
+			return
+		}
+
+		next(w, r)
+	}
+}
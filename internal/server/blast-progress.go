@@ -0,0 +1,30 @@
+package server
+
+import (
+	"afrus-whatsapp-evolution_api-notification/internal/usecase"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+func (s *Server) handleBlastProgress(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	handler := usecase.NewBlastProgressUseCase(r.Context(), s.Configs, s.Databases.EventsDB)
+	report, err := handler.Report(id)
+	if err != nil {
+		log.Printf("[BLAST] - Error reporting blast progress: %v", err)
+		writeError(w, http.StatusInternalServerError, "error reporting blast progress")
+		return
+	}
+	if report == nil {
+		writeError(w, http.StatusNotFound, "blast progress not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	config "afrus-whatsapp-evolution_api-notification/configs"
+	"afrus-whatsapp-evolution_api-notification/internal/services"
+	"afrus-whatsapp-evolution_api-notification/pkg/db"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/netip"
+	"time"
+)
+
+const defaultPort = "3008"
+
+type Server struct {
+	Configs               *config.Config
+	Databases             *db.DBConnections
+	WhatsappSenderService *services.WhatsappSenderService
+	trustedProxies        []netip.Prefix
+	httpServer            *http.Server
+}
+
+func NewServer(configs *config.Config, databases *db.DBConnections, whatsappSenderService *services.WhatsappSenderService) *Server {
+	s := &Server{
+		Configs:               configs,
+		Databases:             databases,
+		WhatsappSenderService: whatsappSenderService,
+		trustedProxies:        parseTrustedProxies(configs.TrustedProxies),
+	}
+
+	port := configs.ServerPort
+	if port == "" {
+		port = defaultPort
+	}
+
+	s.httpServer = &http.Server{
+		Addr:              fmt.Sprintf(":%s", port),
+		Handler:           s.routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	return s
+}
+
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /health", s.handleHealth)
+	mux.HandleFunc("POST /webhook/evolution", s.requireWebhookSecret(s.handleEvolutionWebhook))
+	mux.HandleFunc("POST /webhook/evolution/{token}", s.requireWebhookSecret(s.handleEvolutionWebhook))
+	mux.HandleFunc("POST /webhook/evolution/{token}/{event}", s.requireWebhookSecret(s.handleEvolutionWebhook))
+	mux.HandleFunc("GET /suppressions", s.requireAPIKey(s.handleListSuppressions))
+	mux.HandleFunc("POST /suppressions", s.requireAPIKey(s.handleCreateSuppression))
+	mux.HandleFunc("DELETE /suppressions/{id}", s.requireAPIKey(s.handleDeleteSuppression))
+	mux.HandleFunc("GET /instances/warmup", s.requireAPIKey(s.handleInstanceWarmup))
+	mux.HandleFunc("GET /blasts/{id}/progress", s.requireAPIKey(s.handleBlastProgress))
+	mux.HandleFunc("GET /{code}", s.handleShortLink)
+
+	return mux
+}
+
+// Start blocks serving HTTP requests until the server is shut down.
+func (s *Server) Start() error {
+	log.Printf("[SERVER] - Listening on %s", s.httpServer.Addr)
+	if err := s.httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to start HTTP server: %w", err)
+	}
+	return nil
+}
+
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.httpServer.Shutdown(ctx)
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
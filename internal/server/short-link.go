@@ -0,0 +1,96 @@
+package server
+
+import (
+	"afrus-whatsapp-evolution_api-notification/internal/usecase"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+func (s *Server) handleShortLink(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+
+	handler := usecase.NewShortLinkClickUseCase(r.Context(), s.Configs, s.Databases.EventsDB)
+	target, err := handler.Execute(code, r.UserAgent(), clientIP(r, s.trustedProxies))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		log.Printf("[SHORT LINK] - Error resolving code %s: %v", code, err)
+		writeError(w, http.StatusInternalServerError, "error resolving link")
+		return
+	}
+
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// clientIP returns the address of the client that followed the link.
+// X-Forwarded-For is only read when the request comes from a trusted proxy,
+// and then only the right-most address no trusted proxy added, since
+// anything to its left is whatever the client chose to send.
+func clientIP(r *http.Request, trustedProxies []netip.Prefix) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !isTrustedProxy(host, trustedProxies) {
+		return host
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		ip := strings.TrimSpace(forwarded[i])
+		if ip == "" {
+			continue
+		}
+		if !isTrustedProxy(ip, trustedProxies) {
+			return ip
+		}
+		host = ip
+	}
+	return host
+}
+
+func isTrustedProxy(ip string, trustedProxies []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTrustedProxies parses a comma-separated list of IPs and CIDRs,
+// skipping invalid entries.
+func parseTrustedProxies(value string) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			log.Printf("[SHORT LINK] - Ignoring invalid trusted proxy %q", entry)
+			continue
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted := parseTrustedProxies("10.0.0.0/8, 192.168.1.1, not-an-ip")
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		trusted    bool
+		want       string
+	}{
+		{name: "no proxy configured", remoteAddr: "203.0.113.7:5000", forwarded: "1.2.3.4", want: "203.0.113.7"},
+		{name: "untrusted peer", remoteAddr: "203.0.113.7:5000", forwarded: "1.2.3.4", trusted: true, want: "203.0.113.7"},
+		{name: "trusted proxy", remoteAddr: "10.1.2.3:5000", forwarded: "198.51.100.9", trusted: true, want: "198.51.100.9"},
+		{name: "spoofed entries ignored", remoteAddr: "10.1.2.3:5000", forwarded: "1.2.3.4, 198.51.100.9", trusted: true, want: "198.51.100.9"},
+		{name: "chained trusted proxies", remoteAddr: "10.1.2.3:5000", forwarded: "1.2.3.4, 198.51.100.9, 192.168.1.1", trusted: true, want: "198.51.100.9"},
+		{name: "trusted proxy without header", remoteAddr: "10.1.2.3:5000", trusted: true, want: "10.1.2.3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/abc", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				r.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			var proxies []netip.Prefix
+			if tt.trusted {
+				proxies = trusted
+			}
+			if got := clientIP(r, proxies); got != tt.want {
+				t.Errorf("clientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"afrus-whatsapp-evolution_api-notification/internal/application/dto"
+	"afrus-whatsapp-evolution_api-notification/internal/domain/models"
+	"afrus-whatsapp-evolution_api-notification/internal/usecase"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+func (s *Server) handleListSuppressions(w http.ResponseWriter, r *http.Request) {
+	organizationID, err := strconv.Atoi(r.URL.Query().Get("organization_id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "organization_id is required")
+		return
+	}
+
+	handler := usecase.NewSuppressionUseCase(r.Context(), s.Configs, s.Databases.EventsDB)
+	suppressions, err := handler.List(organizationID, r.URL.Query().Get("phone"))
+	if err != nil {
+		log.Printf("[SUPPRESSION] - Error listing suppressions: %v", err)
+		writeError(w, http.StatusInternalServerError, "error listing suppressions")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, suppressions)
+}
+
+func (s *Server) handleCreateSuppression(w http.ResponseWriter, r *http.Request) {
+	var request dto.SuppressionRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid body")
+		return
+	}
+
+	handler := usecase.NewSuppressionUseCase(r.Context(), s.Configs, s.Databases.EventsDB)
+	suppression, err := handler.Add(request, models.SuppressionSourceAPI)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidSuppression) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			writeError(w, http.StatusConflict, "suppression already exists")
+			return
+		}
+		log.Printf("[SUPPRESSION] - Error creating suppression: %v", err)
+		writeError(w, http.StatusInternalServerError, "error creating suppression")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, suppression)
+}
+
+func (s *Server) handleDeleteSuppression(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	organizationID, err := strconv.Atoi(r.URL.Query().Get("organization_id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "organization_id is required")
+		return
+	}
+
+	handler := usecase.NewSuppressionUseCase(r.Context(), s.Configs, s.Databases.EventsDB)
+	if err := handler.Remove(organizationID, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			writeError(w, http.StatusNotFound, "suppression not found")
+			return
+		}
+		log.Printf("[SUPPRESSION] - Error deleting suppression: %v", err)
+		writeError(w, http.StatusInternalServerError, "error deleting suppression")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,26 @@
+package server
+
+import (
+	"afrus-whatsapp-evolution_api-notification/internal/usecase"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+func (s *Server) handleInstanceWarmup(w http.ResponseWriter, r *http.Request) {
+	organizationID, err := strconv.Atoi(r.URL.Query().Get("organization_id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "organization_id is required")
+		return
+	}
+
+	handler := usecase.NewInstanceWarmupUseCase(r.Context(), s.Configs, s.Databases.Afrus, s.Databases.EventsDB)
+	reports, err := handler.Report(organizationID)
+	if err != nil {
+		log.Printf("[WARMUP] - Error reporting instance warm-up: %v", err)
+		writeError(w, http.StatusInternalServerError, "error reporting instance warm-up")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, reports)
+}
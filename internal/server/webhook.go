@@ -0,0 +1,41 @@
+package server
+
+import (
+	"afrus-whatsapp-evolution_api-notification/internal/application/dto"
+	"afrus-whatsapp-evolution_api-notification/internal/usecase"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+)
+
+const maxWebhookBodySize = 1 << 20
+
+func (s *Server) handleEvolutionWebhook(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBodySize))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid body")
+		return
+	}
+
+	var event dto.EvolutionWebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid body")
+		return
+	}
+
+	var handler interface{ Execute(event string) error }
+	if event.EventName() == dto.EvolutionEventMessagesUpsert {
+		handler = usecase.NewReceiptWebhookInboundMessageUseCase(r.Context(), s.Configs, s.Databases.Afrus, s.Databases.EventsDB, s.WhatsappSenderService)
+	} else {
+		handler = usecase.NewReceiptWebhookStatusEventUseCase(r.Context(), s.Configs, s.Databases.EventsDB)
+	}
+
+	if err := handler.Execute(string(body)); err != nil {
+		log.Printf("[WEBHOOK] - Error processing webhook: %v", err)
+		writeError(w, http.StatusInternalServerError, "error processing webhook")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
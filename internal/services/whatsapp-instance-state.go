@@ -0,0 +1,53 @@
+package services
+
+import (
+	"afrus-whatsapp-evolution_api-notification/internal/domain/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type connectionStateResponse struct {
+	Instance struct {
+		InstanceName string `json:"instanceName"`
+		State        string `json:"state"`
+	} `json:"instance"`
+}
+
+// ConnectionState asks Evolution API whether the instance is connected to
+// WhatsApp ("open", "connecting" or "close").
+func (wss *WhatsappSenderService) ConnectionState(ctx context.Context, instance *models.WhatsappInstance) (string, error) {
+	requestUrl := fmt.Sprintf("%s/instance/connectionState/%s", strings.TrimSuffix(wss.Configs.EvolutionAPIBaseURL, "/"), instance.InstanceName)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", requestUrl, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Add("apikey", wss.Configs.EvolutionAPIKey)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Evolution answers 404 for instances that were deleted or never connected
+	if resp.StatusCode == http.StatusNotFound {
+		return models.ConnectionStateClose, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var state connectionStateResponse
+	if err := json.NewDecoder(resp.Body).Decode(&state); err != nil {
+		return "", fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if state.Instance.State == "" {
+		return "", fmt.Errorf("empty connection state for instance %s", instance.InstanceName)
+	}
+
+	return state.Instance.State, nil
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"afrus-whatsapp-evolution_api-notification/internal/domain/models"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const defaultNumberCheckTTL = 7 * 24 * time.Hour
+
+type whatsappNumbersRequest struct {
+	Numbers []string `json:"numbers"`
+}
+
+type whatsappNumberResult struct {
+	Exists bool   `json:"exists"`
+	Jid    string `json:"jid"`
+	Number string `json:"number"`
+}
+
+// IsOnWhatsapp reports whether the lead phone has a WhatsApp account. Results
+// are cached per phone; when the check is disabled every number is assumed to
+// exist.
+func (wss *WhatsappSenderService) IsOnWhatsapp(ctx context.Context, lead *models.Lead, instance *models.WhatsappInstance) (bool, error) {
+	if !wss.Configs.WhatsappNumberCheckEnabled {
+		return true, nil
+	}
+
+	number := lead.Phone
+	cached, err := wss.NumberChecks.Find(ctx, number)
+	if err != nil {
+		return false, fmt.Errorf("failed to read number check cache: %w", err)
+	}
+	if cached != nil {
+		return cached.Exists, nil
+	}
+
+	result, err := wss.checkWhatsappNumber(ctx, instance, number)
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	check := &models.NumberCheck{
+		Phone:     number,
+		Exists:    result.Exists,
+		Jid:       result.Jid,
+		CheckedAt: now,
+		ExpiresAt: now.Add(wss.numberCheckTTL()),
+	}
+	if err := wss.NumberChecks.Save(ctx, check); err != nil {
+		return false, fmt.Errorf("failed to save number check: %w", err)
+	}
+
+	return result.Exists, nil
+}
+
+func (wss *WhatsappSenderService) checkWhatsappNumber(ctx context.Context, instance *models.WhatsappInstance, number string) (*whatsappNumberResult, error) {
+	requestUrl := fmt.Sprintf("%s/chat/whatsappNumbers/%s", strings.TrimSuffix(wss.Configs.EvolutionAPIBaseURL, "/"), instance.InstanceName)
+
+	payloadBytes, err := json.Marshal(whatsappNumbersRequest{Numbers: []string{number}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", requestUrl, bytes.NewReader(payloadBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Add("Content-Type", contentTypeJSON)
+	req.Header.Add("apikey", wss.Configs.EvolutionAPIKey)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var results []whatsappNumberResult
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("empty response checking number %s", number)
+	}
+
+	return &results[0], nil
+}
+
+func (wss *WhatsappSenderService) numberCheckTTL() time.Duration {
+	if wss.Configs.WhatsappNumberCheckTTLHours <= 0 {
+		return defaultNumberCheckTTL
+	}
+	return time.Duration(wss.Configs.WhatsappNumberCheckTTLHours) * time.Hour
+}
@@ -3,6 +3,7 @@ package services
 import (
 	config "afrus-whatsapp-evolution_api-notification/configs"
 	"afrus-whatsapp-evolution_api-notification/internal/domain/models"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -88,12 +89,41 @@ type WhatsappAttachement struct {
 	Type     uint
 }
 
+// StatusError is returned when Evolution API answers a send with an
+// unexpected status.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.StatusCode)
+}
+
+// Rejected reports whether Evolution API rejected the request itself (e.g.
+// invalid content or media), which sending it again won't change.
+func (e *StatusError) Rejected() bool {
+	switch e.StatusCode {
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity:
+		return true
+	}
+	return false
+}
+
+// NumberCheckStore caches the results of WhatsApp number checks.
+type NumberCheckStore interface {
+	Find(ctx context.Context, phone string) (*models.NumberCheck, error)
+	Save(ctx context.Context, check *models.NumberCheck) error
+}
+
+// WhatsappSenderService sends to lead.Phone as given, so callers must have
+// normalized it to international digits first.
 type WhatsappSenderService struct {
-	Configs *config.Config
+	Configs      *config.Config
+	NumberChecks NumberCheckStore
 }
 
-func NewWhatsappSenderService(configs *config.Config) *WhatsappSenderService {
-	return &WhatsappSenderService{Configs: configs}
+func NewWhatsappSenderService(configs *config.Config, numberChecks NumberCheckStore) *WhatsappSenderService {
+	return &WhatsappSenderService{Configs: configs, NumberChecks: numberChecks}
 }
 
 func (wss *WhatsappSenderService) sendRequest(requestUrl string, payloadBytes []byte) (*WhatsappResponse, error) {
@@ -114,7 +144,7 @@ func (wss *WhatsappSenderService) sendRequest(requestUrl string, payloadBytes []
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusCreated {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, &StatusError{StatusCode: resp.StatusCode}
 	}
 
 	bodyBytes, err := io.ReadAll(resp.Body)
@@ -133,10 +163,8 @@ func (wss *WhatsappSenderService) sendRequest(requestUrl string, payloadBytes []
 func (wss *WhatsappSenderService) SendWhatsappTextMessage(lead *models.Lead, instance *models.WhatsappInstance, content string) (*WhatsappResponse, error) {
 	requestUrl := fmt.Sprintf("%s/message/sendText/%s", strings.TrimSuffix(wss.Configs.EvolutionAPIBaseURL, "/"), instance.InstanceName)
 
-	to := wss.FormatLeadPhone(lead)
-
 	body := Payload{
-		Number: to,
+		Number: lead.Phone,
 		TextMessage: &TextMessage{
 			Text: content,
 		},
@@ -167,10 +195,8 @@ func (wss *WhatsappSenderService) SendWhatsappMediaMessage(lead *models.Lead, in
 		mimeType = mediaTypeMap[mediaKey]
 	}
 
-	to := wss.FormatLeadPhone(lead)
-
 	body := Payload{
-		Number: to,
+		Number: lead.Phone,
 		MediaMessage: &MediaMessage{
 			MediaType: mediaType,
 			MimeType:  mimeType,
@@ -191,10 +217,3 @@ func (wss *WhatsappSenderService) SendWhatsappMediaMessage(lead *models.Lead, in
 
 	return wss.sendRequest(requestUrl, payloadBytes)
 }
-
-func (wss *WhatsappSenderService) FormatLeadPhone(lead *models.Lead) string {
-	lead.Phone = strings.ReplaceAll(lead.Phone, "+", "")
-	lead.Phone = strings.ReplaceAll(lead.Phone, "-", "")
-	lead.Phone = strings.ReplaceAll(lead.Phone, " ", "")
-	return lead.Phone
-}
@@ -6,15 +6,19 @@ import (
 	"afrus-whatsapp-evolution_api-notification/internal/application/repositories"
 	"afrus-whatsapp-evolution_api-notification/internal/domain/models"
 	"afrus-whatsapp-evolution_api-notification/internal/services"
+	"afrus-whatsapp-evolution_api-notification/pkg/i18n"
 	"afrus-whatsapp-evolution_api-notification/pkg/queue"
+	"afrus-whatsapp-evolution_api-notification/pkg/ratelimit"
+	"afrus-whatsapp-evolution_api-notification/pkg/template"
+	"afrus-whatsapp-evolution_api-notification/pkg/utm"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"strconv"
 	"time"
 
-	"golang.org/x/exp/rand"
 	"gorm.io/gorm"
 )
 
@@ -42,19 +46,58 @@ func (rwe *ReceiptAutoresponderEventUseCase) Execute(event string) error {
 	var data dto.AutoresponderEventProcess
 	if err := json.Unmarshal([]byte(event), &data); err != nil {
 		log.Printf("Error to decode JSON: %v", err)
+		return queue.Permanent(err)
+	}
+
+	idempotencyKey := models.NewIdempotencyKey(data.OrganizationID, data.LeadID, "whatsapp_triggers", strconv.Itoa(data.WhatsappTriggerID), idempotencyTTL(rwe.Configs))
+	processed, err := claimMessage(rwe.Ctx, rwe.EventsDB, idempotencyKey)
+	if err != nil {
 		return err
 	}
+	if processed != nil {
+		if processed.Status == models.IdempotencyStatusProcessing {
+			log.Printf("[AUTORESPONDER] - Message for lead: %d is being sent by another delivery", data.LeadID)
+			return requeueClaimed(rwe.Ctx, rwe.Configs, rwe.Queue, rwe.Configs.EvolutionAPINotificationAutoresponderRoutingKey, event, processed)
+		}
+		if processed.PendingBillingEvent != "" {
+			log.Printf("[AUTORESPONDER] - Publishing pending billing of already sent message for lead: %d", data.LeadID)
+			return publishPendingBilling(rwe.Ctx, rwe.Configs, rwe.Queue, rwe.EventsDB, processed)
+		}
+		log.Printf("[AUTORESPONDER] - Skipping already processed message for lead: %d - whatsappTriggerId: %d", data.LeadID, data.WhatsappTriggerID)
+		return nil
+	}
+	defer releaseMessage(rwe.Ctx, rwe.EventsDB, idempotencyKey.Key)
 
 	leadRepo := repositories.NewLeadRepository(rwe.AfrusDB)
 	lead, err := leadRepo.FindById(rwe.Ctx, data.LeadID)
+	if err != nil {
+		return permanentIfMissing(err)
+	}
+
+	settingsRepo := repositories.NewOrganizationSettingsRepository(rwe.EventsDB)
+	settings, err := settingsRepo.FindByOrganization(rwe.Ctx, data.OrganizationID)
 	if err != nil {
 		return err
 	}
 
+	if err := normalizeLeadPhone(rwe.Configs, settings, lead); err != nil {
+		log.Printf("[AUTORESPONDER] - Invalid phone for lead: %d - %v", lead.ID, err)
+		return rwe.StoreEventWithDetails("failed", data, lead, models.JSONB{"reason": err.Error()})
+	}
+
+	suppression, err := findSuppression(rwe.Ctx, rwe.EventsDB, data.OrganizationID, lead)
+	if err != nil {
+		return err
+	}
+	if suppression != nil {
+		log.Printf("[AUTORESPONDER] - Lead: %d is suppressed by %s entry: %d - canceling message", lead.ID, suppression.Scope(), suppression.ID)
+		return rwe.StoreEventWithDetails("canceled", data, lead, suppressionDetails(suppression))
+	}
+
 	whatsappInstanceRepo := repositories.NewWhatsappInstanceRepository(rwe.AfrusDB)
 	whatsappInstance, err := whatsappInstanceRepo.GetWhatsappInstanceById(rwe.Ctx, data.WhatsappInstanceID)
 	if err != nil {
-		return err
+		return permanentIfMissing(err)
 	}
 
 	whatsappInstances, err := whatsappInstanceRepo.GetWhatsappInstancesByOrganization(rwe.Ctx, whatsappInstance)
@@ -62,10 +105,25 @@ func (rwe *ReceiptAutoresponderEventUseCase) Execute(event string) error {
 		return err
 	}
 
+	whatsappInstance, whatsappInstances, err = connectedInstances(whatsappInstance, whatsappInstances)
+	if err != nil {
+		return err
+	}
+
+	// The selected instance sends the message and the rest are fallbacks
+	ordered := NewInstanceSelector(settings.InstanceSelection).Order(append([]models.WhatsappInstance{*whatsappInstance}, whatsappInstances...), lead)
+	ordered, err = preferLeadSender(rwe.Ctx, rwe.EventsDB, data.OrganizationID, lead, ordered)
+	if err != nil {
+		return err
+	}
+	if data.NotBefore != nil {
+		ordered = preferInstance(ordered, uint(data.WhatsappInstanceID))
+	}
+
 	whatsappTriggerRepo := repositories.NewWhatsappTriggerRepository(rwe.AfrusDB)
 	whatsappTrigger, err := whatsappTriggerRepo.GetWhatsappTriggerById(rwe.Ctx, data.WhatsappTriggerID)
 	if err != nil {
-		return err
+		return permanentIfMissing(err)
 	}
 
 	whatsappTriggerAttachmentsRepo := repositories.NewWhatsappTriggerAttachmentRepository(rwe.AfrusDB)
@@ -74,195 +132,332 @@ func (rwe *ReceiptAutoresponderEventUseCase) Execute(event string) error {
 		return err
 	}
 
-	if err := rwe.processRules(data, whatsappInstance, whatsappTrigger); err != nil {
-		return err
+	// Prefer the variant in the lead's language, falling back to the event content
+	content := data.Content
+	if variant, ok := i18n.Select(whatsappTrigger.ContentVariants(), lead.LanguageCode, i18n.ParseFallbacks(rwe.Configs.ContentFallbackLanguages)); ok {
+		content = variant
 	}
 
-	if err := whatsappInstanceRepo.Update(rwe.Ctx, whatsappInstance); err != nil {
-		return fmt.Errorf("error updating whatsapp instance data: %v", err)
+	content, err = template.Render(content, template.Variables(lead.TemplateVariables()))
+	if err != nil {
+		log.Printf("[AUTORESPONDER] - Error personalizing content for lead: %d - %v", lead.ID, err)
+		return rwe.StoreEventWithDetails("failed", data, lead, models.JSONB{"reason": err.Error()})
 	}
 
+	utmParams := utm.NewParams(whatsappTrigger.GASource, whatsappTrigger.GAMedium, whatsappTrigger.GAName, whatsappTrigger.GAContent)
+	content = utm.TagText(content, utmParams)
+	for i, attachment := range attachments {
+		if attachment.Type == uint(services.LINK) {
+			attachments[i].Content = utm.TagText(attachment.Content, utmParams)
+		}
+	}
+
+	// Links are shortened right before the first send, so rescheduled and
+	// paced messages don't leave short links behind for every delivery
+	shortener := newLinkShortener(rwe.Ctx, rwe.Configs, rwe.EventsDB, lead, "whatsapp_triggers", strconv.Itoa(data.WhatsappTriggerID))
+	shortened := false
+
 	var resp *services.WhatsappResponse
+	var sentInstance *models.WhatsappInstance
+	var sendErr error
 
-	// TODO: Move this to a helper function
-	if len(attachments) == 0 {
-		resp, err = rwe.whatsappSenderService.SendWhatsappTextMessage(lead, whatsappInstance, data.Content)
+	// Rate limited instances may take the message later, on the one that
+	// frees up first
+	var limitErr *ratelimit.LimitError
+	var limitedInstance *models.WhatsappInstance
+
+	for i := range ordered {
+		instance := &ordered[i]
+
+		onWhatsapp, err := rwe.whatsappSenderService.IsOnWhatsapp(rwe.Ctx, lead, instance)
 		if err != nil {
-			fmt.Printf("Failed to send message in main instance %s - %v\n", whatsappInstance.InstanceName, err)
-			for _, instance := range whatsappInstances {
-				resp, err = rwe.whatsappSenderService.SendWhatsappTextMessage(lead, &instance, data.Content)
-				if err != nil {
-					fmt.Printf("[Failed to send message in %s] - %v\n", instance.InstanceName, err)
-				} else {
-					if err := rwe.StoreEvent("sent", data, lead, resp); err != nil {
-						return err
+			log.Printf("[AUTORESPONDER] - Error checking whatsapp number in instance: %s - %v - Trying with the next instance", instance.InstanceName, err)
+			sendErr = fmt.Errorf("error checking whatsapp number: %v", err)
+			continue
+		}
+		if !onWhatsapp {
+			log.Printf("[AUTORESPONDER] - Lead: %d phone %s is not on WhatsApp - skipping message", lead.ID, lead.Phone)
+			return rwe.StoreEventWithDetails("failed", data, lead, models.JSONB{"reason": ErrNotOnWhatsapp.Error()})
+		}
+
+		// A paced message already holds its reservation on the instance
+		slot, due := duePacedSlot(data.NotBefore)
+		if !due || data.WhatsappInstanceID != int(instance.ID) {
+			slot, err = reserveInstanceSend(rwe.Ctx, rwe.Configs, rwe.AfrusDB, rwe.EventsDB, settings, instance)
+			if err != nil {
+				var instanceLimit *ratelimit.LimitError
+				if errors.As(err, &instanceLimit) {
+					if limitErr == nil || instanceLimit.RetryAfter < limitErr.RetryAfter {
+						limitErr, limitedInstance = instanceLimit, instance
 					}
-					break
+				} else {
+					sendErr = fmt.Errorf("error reserving whatsapp instance send: %v", err)
 				}
+				log.Printf("[AUTORESPONDER] - Error reserving send in instance: %s - %v - Trying with the next instance", instance.InstanceName, err)
+				continue
 			}
+		}
+
+		if wait := time.Until(slot); wait > pacingTolerance {
+			return rwe.reschedule(data, instance, whatsappTrigger, &slot, wait, fmt.Errorf("paced for %s", slot.Format(time.RFC3339)))
+		}
+
+		if !shortened {
+			content, err = shortener.Shorten(content)
 			if err != nil {
-				if storeErr := rwe.StoreEvent("failed", data, lead, resp); storeErr != nil {
-					return storeErr
-				}
-				return err
-			}
-		} else {
-			if err := rwe.StoreEvent("sent", data, lead, resp); err != nil {
+				rwe.releaseSend(settings, instance, false)
 				return err
 			}
+			shortened = true
 		}
-	} else {
-		for _, attachment := range attachments {
-			whatsappAttachment := services.WhatsappAttachement{
-				Type:     attachment.Type,
-				Content:  attachment.Content,
-				Filename: attachment.Filename,
-				Size:     attachment.Size,
-			}
-			resp, err = rwe.whatsappSenderService.SendWhatsappMediaMessage(lead, whatsappInstance, whatsappAttachment, data.Content)
-			if err != nil {
-				fmt.Printf("[Failed to send media message to main instance %s] - %v\n", whatsappInstance.InstanceName, err)
-				if storeErr := rwe.StoreEvent("failed", data, lead, resp); storeErr != nil {
-					return storeErr
-				}
-				return err
+
+		var delivered int
+		resp, delivered, err = rwe.sendMessage(lead, instance, content, attachments)
+		if err != nil && delivered == 0 {
+			log.Printf("[AUTORESPONDER] - Failed to send message in instance: %s - %v - Trying with the next instance", instance.InstanceName, err)
+			rwe.releaseSend(settings, instance, true)
+			sendErr = err
+			continue
+		}
+		if err != nil {
+			// Retrying would resend the attachments the lead already got, so the
+			// message counts as sent and the missing attachments as failed
+			rwe.recordFailure(settings, instance)
+			log.Printf("[AUTORESPONDER] - Sent %d of %d attachments to lead: %d - %v", delivered, len(attachments), lead.ID, err)
+			if storeErr := rwe.StoreEventWithDetails("failed", data, lead, models.JSONB{
+				"reason":                err.Error(),
+				"attachment_id":         attachments[delivered].ID,
+				"delivered_attachments": delivered,
+				"total_attachments":     len(attachments),
+			}); storeErr != nil {
+				log.Printf("[AUTORESPONDER] - Error storing failed attachment event: %v", storeErr)
 			}
 		}
-		if err := rwe.StoreEvent("sent", data, lead, resp); err != nil {
+
+		// Without the key a redelivery would send the message again
+		if err := rwe.StoreSentEvent(data, lead, instance, resp, delivered, idempotencyKey); err != nil {
 			return err
 		}
+		sentInstance = instance
+		break
 	}
 
-	err = rwe.SendEventToBilling()
-	if err != nil {
+	if sentInstance == nil {
+		if limitErr != nil {
+			return rwe.reschedule(data, limitedInstance, whatsappTrigger, nil, limitErr.RetryAfter, limitErr)
+		}
+
+		// The failed event is stored by DeadLettered once no retry is left
+		log.Printf("[AUTORESPONDER] - No instance could send the message to lead: %d", lead.ID)
+		return permanentIfRejected(sendErr)
+	}
+
+	if err := shortener.AttachMessageID(resp.Key.ID); err != nil {
+		log.Printf("[AUTORESPONDER] - Error attaching message id to short links: %v", err)
+	}
+
+	if err := rememberLeadSender(rwe.Ctx, rwe.EventsDB, data.OrganizationID, lead.ID, sentInstance); err != nil {
+		log.Printf("[AUTORESPONDER] - Error storing lead sender: %v", err)
+	}
+
+	if err := publishPendingBilling(rwe.Ctx, rwe.Configs, rwe.Queue, rwe.EventsDB, idempotencyKey); err != nil {
 		return err
 	}
 
-	log.Printf("[MESSAGE] - Message processed successfully - [Name: %s / Owner: %s / To: %s / Lead: %s] \n", whatsappTrigger.Name, whatsappInstance.Owner, lead.Phone, lead.Email)
+	log.Printf("[MESSAGE] - Message processed successfully - [Name: %s / Owner: %s / To: %s / Lead: %s] \n", whatsappTrigger.Name, sentInstance.Owner, lead.Phone, lead.Email)
 
 	return nil
 }
 
-func (rwe *ReceiptAutoresponderEventUseCase) processRules(data dto.AutoresponderEventProcess, whatsappInstance *models.WhatsappInstance, whatsappTrigger *models.WhatsappTrigger) error {
-	if err := rwe.maxConsecutivesSent(data, whatsappInstance, whatsappTrigger); err != nil {
-		return err
+// DeadLettered records a message the retry policy gave up on as failed.
+// Messages that were sent are left alone.
+func (rwe *ReceiptAutoresponderEventUseCase) DeadLettered(event string, cause error) error {
+	var data dto.AutoresponderEventProcess
+	if err := json.Unmarshal([]byte(event), &data); err != nil {
+		return fmt.Errorf("error decoding dead-lettered message: %v", err)
 	}
-	if err := rwe.maxSentRate(data, whatsappInstance, whatsappTrigger); err != nil {
+
+	idempotencyKey := models.NewIdempotencyKey(data.OrganizationID, data.LeadID, "whatsapp_triggers", strconv.Itoa(data.WhatsappTriggerID), idempotencyTTL(rwe.Configs))
+	idempotencyKeyRepo := repositories.NewIdempotencyKeyRepository(rwe.EventsDB)
+	processed, err := idempotencyKeyRepo.Find(rwe.Ctx, idempotencyKey.Key)
+	if err != nil {
 		return err
 	}
-	if err := rwe.sleepTime(); err != nil {
-		return err
+	if processed != nil && processed.Status == models.IdempotencyStatusSent {
+		return nil
 	}
-	return nil
+
+	leadRepo := repositories.NewLeadRepository(rwe.AfrusDB)
+	lead, err := leadRepo.FindById(rwe.Ctx, data.LeadID)
+	if err != nil {
+		log.Printf("[AUTORESPONDER] - Dead-lettered message for missing lead: %d - %v", data.LeadID, err)
+		lead = &models.Lead{ID: data.LeadID, OrganizationID: data.OrganizationID}
+	}
+
+	log.Printf("[AUTORESPONDER] - Message to lead: %d dead-lettered - %v", data.LeadID, cause)
+	return rwe.StoreEventWithDetails("failed", data, lead, models.JSONB{
+		"reason":        cause.Error(),
+		"dead_lettered": true,
+	})
 }
 
-func (rwe *ReceiptAutoresponderEventUseCase) maxConsecutivesSent(data dto.AutoresponderEventProcess, whatsappInstance *models.WhatsappInstance, whatsappTrigger *models.WhatsappTrigger) error {
-	const (
-		baseMaxSends  = 2
-		maxSendsLimit = 6
-	)
+// sendMessage sends the content to lead through instance: as a text message,
+// or as the caption of every attachment. It returns how many attachments
+// were delivered before a failure, so attachments[delivered] is the one that
+// failed.
+func (rwe *ReceiptAutoresponderEventUseCase) sendMessage(lead *models.Lead, instance *models.WhatsappInstance, content string, attachments []models.WhatsappTriggerAttachment) (*services.WhatsappResponse, int, error) {
+	if len(attachments) == 0 {
+		resp, err := rwe.whatsappSenderService.SendWhatsappTextMessage(lead, instance, content)
+		return resp, 0, err
+	}
 
-	monthsSinceCreation := int(time.Since(whatsappInstance.CreatedAt).Hours() / (24 * 30))
+	var resp *services.WhatsappResponse
+	delivered := 0
+	for _, attachment := range attachments {
+		whatsappAttachment := services.WhatsappAttachement{
+			Type:     attachment.Type,
+			Content:  attachment.Content,
+			Filename: attachment.Filename,
+			Size:     attachment.Size,
+		}
+		attachmentResp, err := rwe.whatsappSenderService.SendWhatsappMediaMessage(lead, instance, whatsappAttachment, content)
+		if err != nil {
+			return resp, delivered, err
+		}
+		resp = attachmentResp
+		delivered++
+	}
+	return resp, delivered, nil
+}
 
-	// Calculate max allowed sends (base + 1 per month, capped at maxSendsLimit)
-	maxAllowedSends := baseMaxSends + monthsSinceCreation
-	if maxAllowedSends > maxSendsLimit {
-		maxAllowedSends = maxSendsLimit
+// connectedInstances drops the disconnected instances, promoting the first
+// connected fallback when the trigger instance is down.
+func connectedInstances(main *models.WhatsappInstance, fallbacks []models.WhatsappInstance) (*models.WhatsappInstance, []models.WhatsappInstance, error) {
+	connected := make([]models.WhatsappInstance, 0, len(fallbacks))
+	for _, instance := range fallbacks {
+		if instance.IsConnected() {
+			connected = append(connected, instance)
+		}
 	}
 
-	currentSends, ok := whatsappInstance.Data["consecutive_sends"].(float64)
-	if !ok {
-		whatsappInstance.Data["consecutive_sends"] = baseMaxSends
-		return nil
+	if main.IsConnected() {
+		return main, connected, nil
+	}
+	if len(connected) == 0 {
+		return nil, nil, fmt.Errorf("no connected instance: %s is in state '%s'", main.InstanceName, main.ConnectionState())
 	}
 
-	if int(currentSends) >= maxAllowedSends {
-		if rwe.Configs.Environment == "development" {
-			return nil
-		} else {
-			message := &dto.AutoresponderEventProcess{
-				Content:            data.Content,
-				LeadID:             data.LeadID,
-				OrganizationID:     data.OrganizationID,
-				WhatsappInstanceID: int(whatsappInstance.ID),
-				WhatsappTriggerID:  int(whatsappTrigger.ID),
-			}
+	log.Printf("[AUTORESPONDER] - Instance: %s is in state '%s' - using %s instead", main.InstanceName, main.ConnectionState(), connected[0].InstanceName)
+	return &connected[0], connected[1:], nil
+}
 
-			messageBytes, err := json.Marshal(message)
-			if err != nil {
-				return fmt.Errorf("error marshalling message: %v", err)
-			}
+// reschedule hands the message back to the delayed exchange on the same
+// instance after delay. Paced messages carry their reserved slot as
+// notBefore.
+func (rwe *ReceiptAutoresponderEventUseCase) reschedule(data dto.AutoresponderEventProcess, whatsappInstance *models.WhatsappInstance, whatsappTrigger *models.WhatsappTrigger, notBefore *time.Time, delay time.Duration, reason error) error {
+	message := &dto.AutoresponderEventProcess{
+		Content:            data.Content,
+		LeadID:             data.LeadID,
+		OrganizationID:     data.OrganizationID,
+		WhatsappInstanceID: int(whatsappInstance.ID),
+		WhatsappTriggerID:  int(whatsappTrigger.ID),
+		NotBefore:          notBefore,
+	}
 
-			rwe.Queue.Schedule(
-				rwe.Configs.EvolutionAPINotificationExchange,
-				rwe.Configs.EvolutionAPINotificationAutoresponderRoutingKey,
-				messageBytes,
-				int(time.Minute)*5,
-			)
-		}
-		return fmt.Errorf("max consecutive sends limit reached: %d/%d", int(currentSends), maxAllowedSends)
+	messageBytes, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("error marshalling message: %v", err)
 	}
 
-	whatsappInstance.Data["consecutive_sends"] = currentSends + 1
-	return nil
+	if err := rwe.Queue.Schedule(
+		rwe.Ctx,
+		rwe.Configs.EvolutionAPINotificationExchange,
+		rwe.Configs.EvolutionAPINotificationAutoresponderRoutingKey,
+		messageBytes,
+		delay,
+	); err != nil {
+		return err
+	}
+
+	return fmt.Errorf("%w: %v", ErrMessageRescheduled, reason)
 }
 
-func (rwe *ReceiptAutoresponderEventUseCase) maxSentRate(data dto.AutoresponderEventProcess, whatsappInstance *models.WhatsappInstance, whatsappTrigger *models.WhatsappTrigger) error {
-	const cooldownMinutes = 5
+// recordFailure counts a failed send against the instance warm-up.
+func (rwe *ReceiptAutoresponderEventUseCase) recordFailure(settings *models.OrganizationSettings, instance *models.WhatsappInstance) {
+	if err := recordWarmupFailure(rwe.Ctx, rwe.EventsDB, settings, instance); err != nil {
+		log.Printf("[AUTORESPONDER] - Error recording warm-up failure of instance: %s - %v", instance.InstanceName, err)
+	}
+}
 
-	lastSendStr, ok := whatsappInstance.Data["last_send_time"].(string)
-	if !ok {
-		whatsappInstance.Data["last_send_time"] = time.Now().Format(time.RFC3339)
-		return nil
+// releaseSend gives back the warm-up slot reserved on instance for a send
+// that didn't go out, counting it as a failure when the send failed.
+func (rwe *ReceiptAutoresponderEventUseCase) releaseSend(settings *models.OrganizationSettings, instance *models.WhatsappInstance, failed bool) {
+	if err := releaseWarmupSend(rwe.Ctx, rwe.EventsDB, settings, instance, failed); err != nil {
+		log.Printf("[AUTORESPONDER] - Error releasing warm-up send of instance: %s - %v", instance.InstanceName, err)
 	}
+}
+
+func (rwe *ReceiptAutoresponderEventUseCase) StoreEvent(kind string, data dto.AutoresponderEventProcess, lead *models.Lead, resp *services.WhatsappResponse) error {
+	eventRepo := repositories.NewWhatsappEventRepository(rwe.EventsDB)
 
-	lastSendTime, err := time.Parse(time.RFC3339, lastSendStr)
+	event, err := rwe.newEvent(data, lead, resp)
 	if err != nil {
-		return fmt.Errorf("invalid last send time format: %v", err)
-	}
-
-	// Check if enough time has passed
-	if time.Since(lastSendTime).Minutes() < cooldownMinutes {
-		if rwe.Configs.Environment == "development" {
-			return nil
-		} else {
-			message := &dto.AutoresponderEventProcess{
-				Content:            data.Content,
-				LeadID:             data.LeadID,
-				OrganizationID:     data.OrganizationID,
-				WhatsappInstanceID: int(whatsappInstance.ID),
-				WhatsappTriggerID:  int(whatsappTrigger.ID),
-			}
+		return err
+	}
 
-			messageBytes, err := json.Marshal(message)
-			if err != nil {
-				return fmt.Errorf("error marshalling message: %v", err)
-			}
+	if err := eventRepo.Save(rwe.Ctx, kind, event); err != nil {
+		return fmt.Errorf("[EVENT] - error saving event: %v", err)
+	}
+	return nil
+}
 
-			rwe.Queue.Schedule(
-				rwe.Configs.EvolutionAPINotificationExchange,
-				rwe.Configs.EvolutionAPINotificationAutoresponderRoutingKey,
-				messageBytes,
-				cooldownMinutes,
-			)
-		}
+// StoreEventWithDetails records an event that has no Evolution response,
+// keeping details (e.g. the failure reason) as the event payload.
+func (rwe *ReceiptAutoresponderEventUseCase) StoreEventWithDetails(kind string, data dto.AutoresponderEventProcess, lead *models.Lead, details models.JSONB) error {
+	eventRepo := repositories.NewWhatsappEventRepository(rwe.EventsDB)
 
-		return fmt.Errorf("message rate limit: the message was scheduled for %d", cooldownMinutes)
+	event, err := rwe.newEvent(data, lead, nil)
+	if err != nil {
+		return err
 	}
+	event.Event = details
 
-	whatsappInstance.Data["last_send_time"] = time.Now().Format(time.RFC3339)
+	if err := eventRepo.Save(rwe.Ctx, kind, event); err != nil {
+		return fmt.Errorf("[EVENT] - error saving event: %v", err)
+	}
 	return nil
 }
 
-func (rwe *ReceiptAutoresponderEventUseCase) sleepTime() error {
-	randomDelay := time.Duration(rand.Intn(60)+1) * time.Second
-	time.Sleep(randomDelay)
+// StoreSentEvent records the sent event together with the idempotency key so
+// redeliveries of the same message are skipped. The key keeps the billing
+// event until publishPendingBilling publishes it.
+func (rwe *ReceiptAutoresponderEventUseCase) StoreSentEvent(data dto.AutoresponderEventProcess, lead *models.Lead, instance *models.WhatsappInstance, resp *services.WhatsappResponse, attachmentCount int, idempotencyKey *models.IdempotencyKey) error {
+	idempotencyKeyRepo := repositories.NewIdempotencyKeyRepository(rwe.EventsDB)
+
+	event, err := rwe.newEvent(data, lead, resp)
+	if err != nil {
+		return err
+	}
+
+	billingEvent, err := rwe.BillingEvent(data, instance, resp, attachmentCount)
+	if err != nil {
+		return err
+	}
+
+	idempotencyKey.MessageID = event.MessageID
+	idempotencyKey.PendingBillingEvent = string(billingEvent)
+	if err := idempotencyKeyRepo.SaveWithEvent(rwe.Ctx, idempotencyKey, "sent", event); err != nil {
+		return fmt.Errorf("[EVENT] - error saving event: %v", err)
+	}
+
+	// Statuses that arrived before the sent event are recorded now
+	if err := replayStatusEvents(rwe.Ctx, rwe.EventsDB, event.MessageID); err != nil {
+		log.Printf("[EVENT] - Error replaying parked status events for message: %s - %v", event.MessageID, err)
+	}
 	return nil
 }
 
-func (rwe *ReceiptAutoresponderEventUseCase) StoreEvent(kind string, data dto.AutoresponderEventProcess, lead *models.Lead, resp *services.WhatsappResponse) error {
-	eventRepo := repositories.NewWhatsappEventRepository(rwe.EventsDB)
-
+func (rwe *ReceiptAutoresponderEventUseCase) newEvent(data dto.AutoresponderEventProcess, lead *models.Lead, resp *services.WhatsappResponse) (*models.WhatsappEvent, error) {
 	var messageID = ""
 	if resp != nil {
 		messageID = resp.Key.ID
@@ -271,13 +466,13 @@ func (rwe *ReceiptAutoresponderEventUseCase) StoreEvent(kind string, data dto.Au
 	var eventMap models.JSONB
 	respBytes, err := json.Marshal(resp)
 	if err != nil {
-		return fmt.Errorf("error marshalling event response: %v", err)
+		return nil, fmt.Errorf("error marshalling event response: %v", err)
 	}
 	if err := json.Unmarshal(respBytes, &eventMap); err != nil {
-		return fmt.Errorf("error unmarshalling event response: %v", err)
+		return nil, fmt.Errorf("error unmarshalling event response: %v", err)
 	}
 
-	event := &models.WhatsappEvent{
+	return &models.WhatsappEvent{
 		LeadID:         data.LeadID,
 		OrganizationID: data.OrganizationID,
 		PhoneNumber:    lead.Phone,
@@ -287,18 +482,30 @@ func (rwe *ReceiptAutoresponderEventUseCase) StoreEvent(kind string, data dto.Au
 		EventType:      1,
 		DateEvent:      time.Now().Format(time.RFC3339),
 		Event:          eventMap,
-	}
+	}, nil
+}
 
-	if err := eventRepo.Save(rwe.Ctx, kind, event); err != nil {
-		return fmt.Errorf("[EVENT] - error saving event: %v", err)
+func (rwe *ReceiptAutoresponderEventUseCase) BillingEvent(data dto.AutoresponderEventProcess, instance *models.WhatsappInstance, resp *services.WhatsappResponse, attachmentCount int) ([]byte, error) {
+	const sourceTable = "whatsapp_triggers"
+
+	billingEvent := dto.BillingEvent{
+		Version:         dto.BillingEventVersion,
+		IdempotencyKey:  dto.BillingIdempotencyKey(sourceTable, data.WhatsappTriggerID, data.LeadID, resp.Key.ID),
+		Channel:         dto.BillingChannel,
+		OrganizationID:  data.OrganizationID,
+		LeadID:          data.LeadID,
+		SourceTable:     sourceTable,
+		SourceID:        data.WhatsappTriggerID,
+		MessageID:       resp.Key.ID,
+		InstanceID:      instance.ID,
+		InstanceName:    instance.InstanceName,
+		AttachmentCount: attachmentCount,
+		Timestamp:       time.Now().Format(time.RFC3339),
 	}
-	return nil
-}
 
-func (rwe *ReceiptAutoresponderEventUseCase) SendEventToBilling() error {
-	err := rwe.Queue.Publish(rwe.Ctx, rwe.Configs.RabbitMQBillingExchange, rwe.Configs.RabbitMQBillingRoutingKey, []byte("receipt"))
+	body, err := json.Marshal(billingEvent)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("error marshalling billing event: %v", err)
 	}
-	return nil
+	return body, nil
 }
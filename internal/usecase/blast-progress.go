@@ -0,0 +1,140 @@
+package usecase
+
+import (
+	config "afrus-whatsapp-evolution_api-notification/configs"
+	"afrus-whatsapp-evolution_api-notification/internal/application/dto"
+	"afrus-whatsapp-evolution_api-notification/internal/application/repositories"
+	"afrus-whatsapp-evolution_api-notification/internal/domain/models"
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type BlastProgressUseCase struct {
+	Ctx      context.Context
+	Configs  *config.Config
+	EventsDB *gorm.DB
+}
+
+func NewBlastProgressUseCase(ctx context.Context, configs *config.Config, eventsDB *gorm.DB) *BlastProgressUseCase {
+	return &BlastProgressUseCase{
+		Ctx:      ctx,
+		Configs:  configs,
+		EventsDB: eventsDB,
+	}
+}
+
+// Report returns the progress of the blast, or nil when none of its messages
+// was processed yet.
+func (bpu *BlastProgressUseCase) Report(communicationWhatsappID int) (*dto.BlastProgressReport, error) {
+	progressRepo := repositories.NewBlastProgressRepository(bpu.EventsDB)
+	progress, err := progressRepo.FindByCommunication(bpu.Ctx, communicationWhatsappID)
+	if err != nil || progress == nil {
+		return nil, err
+	}
+
+	return &dto.BlastProgressReport{
+		CommunicationWhatsappID: progress.CommunicationWhatsappID,
+		OrganizationID:          progress.OrganizationID,
+		Expected:                progress.Expected,
+		Sent:                    progress.Sent,
+		Failed:                  progress.Failed,
+		Canceled:                progress.Canceled,
+		Pending:                 progress.Pending(),
+		Completed:               progress.CompletedAt != nil,
+		CompletedAt:             progress.CompletedAt,
+		UpdatedAt:               progress.UpdatedAt,
+	}, nil
+}
+
+// finishMessage stores the event of a message that reached a final outcome
+// without being sent and counts it in the blast progress.
+func (rbu *ReceiptBlastEventUseCase) finishMessage(outcome string, data dto.BlastEventProcess, lead *models.Lead, details models.JSONB) error {
+	if err := rbu.StoreEventWithDetails(outcome, data, lead, details); err != nil {
+		return err
+	}
+	rbu.trackProgress(data, outcome)
+	return nil
+}
+
+// trackProgress counts the outcome of a message in the blast progress and
+// records a partially_delivered event when it was the last message of a blast
+// with failures. The message is already processed, so errors are only logged.
+func (rbu *ReceiptBlastEventUseCase) trackProgress(data dto.BlastEventProcess, outcome string) {
+	progressRepo := repositories.NewBlastProgressRepository(rbu.EventsDB)
+	expected, err := rbu.expectedLeads(data)
+	if err != nil {
+		// Counted without it, the blast completes once a later message knows it
+		log.Printf("[BLAST] - Error counting leads of communicationWhatsappId: %d - %v", data.CommunicationWhatsappId, err)
+	}
+
+	progress, completed, err := progressRepo.Increment(rbu.Ctx, data.OrganizationID, data.CommunicationWhatsappId, data.LeadID, expected, outcome)
+	if err != nil {
+		log.Printf("[BLAST] - Error tracking progress of communicationWhatsappId: %d - %v", data.CommunicationWhatsappId, err)
+		return
+	}
+	if !completed {
+		return
+	}
+
+	log.Printf("[BLAST] - CommunicationWhatsappId: %d finished - sent: %d - failed: %d - canceled: %d", progress.CommunicationWhatsappID, progress.Sent, progress.Failed, progress.Canceled)
+	if progress.Failed == 0 {
+		return
+	}
+	if err := rbu.storePartiallyDelivered(progress); err != nil {
+		log.Printf("Error storing partially delivered event: %v", err)
+	}
+}
+
+// expectedLeads returns the number of leads of the blast: the count carried
+// by the message, the one already stored in its progress, or else the leads
+// of its communication in the Afrus database.
+func (rbu *ReceiptBlastEventUseCase) expectedLeads(data dto.BlastEventProcess) (int, error) {
+	if data.ExpectedLeads > 0 {
+		return data.ExpectedLeads, nil
+	}
+
+	progressRepo := repositories.NewBlastProgressRepository(rbu.EventsDB)
+	progress, err := progressRepo.FindByCommunication(rbu.Ctx, data.CommunicationWhatsappId)
+	if err != nil {
+		return 0, err
+	}
+	if progress != nil && progress.Expected > 0 {
+		return progress.Expected, nil
+	}
+
+	communicationWhatsappRepo := repositories.NewCommunicationWhatsappRepository(rbu.AfrusDB)
+	return communicationWhatsappRepo.CountLeads(rbu.Ctx, data.CommunicationWhatsappId)
+}
+
+// storePartiallyDelivered records the blast level event of a blast that
+// finished with failures. It belongs to no lead.
+func (rbu *ReceiptBlastEventUseCase) storePartiallyDelivered(progress *models.BlastProgress) error {
+	eventRepo := repositories.NewWhatsappEventRepository(rbu.EventsDB)
+
+	event := &models.WhatsappEvent{
+		OrganizationID: progress.OrganizationID,
+		ExternalID:     strconv.Itoa(progress.CommunicationWhatsappID),
+		ExternalTable:  "communication_whatsapps",
+		EventType:      1,
+		DateEvent:      time.Now().Format(time.RFC3339),
+		Event: models.JSONB{
+			"expected": progress.Expected,
+			"sent":     progress.Sent,
+			"failed":   progress.Failed,
+			"canceled": progress.Canceled,
+		},
+	}
+
+	if err := eventRepo.Save(rbu.Ctx, "partially_delivered", event); err != nil {
+		return fmt.Errorf("[EVENT] - error saving event: %v", err)
+	}
+
+	log.Printf("[EVENT] - Event of type: 'partially_delivered' for communicationWhatsappId: '%d' saved successfully", progress.CommunicationWhatsappID)
+
+	return nil
+}
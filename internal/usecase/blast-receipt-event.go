@@ -6,15 +6,19 @@ import (
 	"afrus-whatsapp-evolution_api-notification/internal/application/repositories"
 	"afrus-whatsapp-evolution_api-notification/internal/domain/models"
 	"afrus-whatsapp-evolution_api-notification/internal/services"
+	"afrus-whatsapp-evolution_api-notification/pkg/i18n"
 	"afrus-whatsapp-evolution_api-notification/pkg/queue"
+	"afrus-whatsapp-evolution_api-notification/pkg/ratelimit"
+	"afrus-whatsapp-evolution_api-notification/pkg/template"
+	"afrus-whatsapp-evolution_api-notification/pkg/utm"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"strconv"
 	"time"
 
-	"golang.org/x/exp/rand"
 	"gorm.io/gorm"
 )
 
@@ -42,51 +46,305 @@ func (rbu *ReceiptBlastEventUseCase) Execute(event string) error {
 	var data dto.BlastEventProcess
 	if err := json.Unmarshal([]byte(event), &data); err != nil {
 		log.Printf("Failed to unmarshal event: %v", err)
+		return queue.Permanent(err)
+	}
+	// data is personalized below, the original is kept to reschedule it
+	original := data
+
+	idempotencyKey := models.NewIdempotencyKey(data.OrganizationID, data.LeadID, "communication_whatsapps", strconv.Itoa(data.CommunicationWhatsappId), idempotencyTTL(rbu.Configs))
+	processed, err := claimMessage(rbu.Ctx, rbu.EventsDB, idempotencyKey)
+	if err != nil {
 		return err
 	}
+	if processed != nil {
+		if processed.Status == models.IdempotencyStatusProcessing {
+			log.Printf("[BLAST] - Message for lead: %d is being sent by another delivery", data.LeadID)
+			return requeueClaimed(rbu.Ctx, rbu.Configs, rbu.Queue, rbu.Configs.EvolutionAPINotificationBlastRoutingKey, event, processed)
+		}
+		if processed.PendingBillingEvent != "" {
+			log.Printf("[BLAST] - Publishing pending billing of already sent message for lead: %d", data.LeadID)
+			return publishPendingBilling(rbu.Ctx, rbu.Configs, rbu.Queue, rbu.EventsDB, processed)
+		}
+		log.Printf("[BLAST] - Skipping already processed message for lead: %d - communicationWhatsappId: %d", data.LeadID, data.CommunicationWhatsappId)
+		return nil
+	}
+	defer releaseMessage(rbu.Ctx, rbu.EventsDB, idempotencyKey.Key)
 
 	leadRepo := repositories.NewLeadRepository(rbu.AfrusDB)
 	lead, err := leadRepo.FindById(rbu.Ctx, data.LeadID)
+	if err != nil {
+		return permanentIfMissing(err)
+	}
+
+	settingsRepo := repositories.NewOrganizationSettingsRepository(rbu.EventsDB)
+	settings, err := settingsRepo.FindByOrganization(rbu.Ctx, data.OrganizationID)
+	if err != nil {
+		return err
+	}
+
+	if err := normalizeLeadPhone(rbu.Configs, settings, lead); err != nil {
+		log.Printf("[BLAST] - Invalid phone for lead: %d - %v", lead.ID, err)
+		return rbu.finishMessage(models.BlastOutcomeFailed, data, lead, models.JSONB{"reason": err.Error()})
+	}
+
+	suppression, err := findSuppression(rbu.Ctx, rbu.EventsDB, data.OrganizationID, lead)
 	if err != nil {
 		return err
 	}
+	if suppression != nil {
+		log.Printf("[BLAST] - Lead: %d is suppressed by %s entry: %d - canceling message", lead.ID, suppression.Scope(), suppression.ID)
+		return rbu.finishMessage(models.BlastOutcomeCanceled, data, lead, suppressionDetails(suppression))
+	}
+
+	opening := nextSendOpening(rbu.Configs, settings, lead)
+	if delay := time.Until(opening); delay > 0 {
+		return rbu.scheduleForOpening(original, lead, opening, delay)
+	}
 
 	communicationWhatsappRepo := repositories.NewCommunicationWhatsappRepository(rbu.AfrusDB)
 	communicationWhatsapp, err := communicationWhatsappRepo.FindById(rbu.Ctx, data.CommunicationWhatsappId)
 	if err != nil {
-		return err
+		return permanentIfMissing(err)
+	}
+
+	// Prefer the variant in the lead's language, falling back to the event content
+	if content, ok := i18n.Select(communicationWhatsapp.ContentVariants(), lead.LanguageCode, i18n.ParseFallbacks(rbu.Configs.ContentFallbackLanguages)); ok {
+		data.Content = content
+	}
+
+	if err := rbu.personalize(&data, lead, communicationWhatsapp); err != nil {
+		log.Printf("[BLAST] - Error personalizing content for lead: %d - %v", lead.ID, err)
+		return rbu.finishMessage(models.BlastOutcomeFailed, data, lead, models.JSONB{"reason": err.Error()})
 	}
 
+	rbu.tagLinks(&data, communicationWhatsapp)
+
+	// Links are shortened right before the first send, so rescheduled and
+	// paced messages don't leave short links behind for every delivery
+	shortener := newLinkShortener(rbu.Ctx, rbu.Configs, rbu.EventsDB, lead, "communication_whatsapps", strconv.Itoa(data.CommunicationWhatsappId))
+	shortened := false
+
 	var resp *services.WhatsappResponse
+	var sentInstance *models.WhatsappInstance
+
+	instances := NewInstanceSelector(settings.InstanceSelection).Order(communicationWhatsapp.WhatsappInstances(), lead)
+	instances, err = preferLeadSender(rbu.Ctx, rbu.EventsDB, data.OrganizationID, lead, instances)
+	if err != nil {
+		return err
+	}
+	if data.NotBefore != nil {
+		instances = preferInstance(instances, uint(data.PacedInstanceID))
+	}
+
+	// Rate limited instances may take the message later, the other failures
+	// are kept as the reason when no instance can
+	var limitErr *ratelimit.LimitError
+	failures := models.JSONB{}
+
+	for _, instance := range instances {
+		if !instance.IsConnected() {
+			log.Printf("[BLAST] - Skipping instance: %s in state '%s'", instance.InstanceName, instance.ConnectionState())
+			failures[instance.InstanceName] = fmt.Sprintf("instance is in state '%s'", instance.ConnectionState())
+			continue
+		}
 
-	for _, instance := range communicationWhatsapp.Instances {
-		err := rbu.processRules(&instance.WhatsappInstance)
+		onWhatsapp, err := rbu.whatsappSenderService.IsOnWhatsapp(rbu.Ctx, lead, &instance)
 		if err != nil {
-			log.Printf("Error processing rules: %v - Trying with the next instance", err)
+			log.Printf("Error checking whatsapp number: %v - Trying with the next instance", err)
+			failures[instance.InstanceName] = err.Error()
 			continue
 		}
+		if !onWhatsapp {
+			log.Printf("[BLAST] - Lead: %d phone %s is not on WhatsApp - skipping message", lead.ID, lead.Phone)
+			return rbu.finishMessage(models.BlastOutcomeFailed, data, lead, models.JSONB{"reason": ErrNotOnWhatsapp.Error()})
+		}
 
-		resp, err = rbu.sendMessage(data, &instance.WhatsappInstance, lead, communicationWhatsapp)
+		// A paced message already holds its reservation on the instance
+		slot, due := duePacedSlot(data.NotBefore)
+		if !due || data.PacedInstanceID != int(instance.ID) {
+			slot, err = reserveInstanceSend(rbu.Ctx, rbu.Configs, rbu.AfrusDB, rbu.EventsDB, settings, &instance)
+			if err != nil {
+				var instanceLimit *ratelimit.LimitError
+				if errors.As(err, &instanceLimit) {
+					if limitErr == nil || instanceLimit.RetryAfter < limitErr.RetryAfter {
+						limitErr = instanceLimit
+					}
+				} else {
+					failures[instance.InstanceName] = err.Error()
+				}
+				log.Printf("Error processing rules: %v - Trying with the next instance", err)
+				continue
+			}
+		}
+
+		if time.Until(slot) > pacingTolerance {
+			return rbu.schedulePaced(original, &instance, lead, slot)
+		}
+
+		if !shortened {
+			if err := rbu.shortenLinks(&data, communicationWhatsapp, shortener); err != nil {
+				rbu.releaseSend(settings, &instance, false)
+				return err
+			}
+			shortened = true
+		}
+
+		resp, err = rbu.sendMessage(data, &instance, lead, communicationWhatsapp)
 		if err != nil {
-			rbu.StoreEvent("failed", data, lead, nil)
+			rbu.releaseSend(settings, &instance, true)
 			log.Printf("Error sending message: %v - Trying with the next instance", err)
+			failures[instance.InstanceName] = err.Error()
 			continue
 		}
 
 		// If message is sent successfully, break the loop
-		log.Printf("[BLAST] - Message sent successfully with instance: %v to: %s", instance.WhatsappInstance.InstanceName, lead.Email)
+		log.Printf("[BLAST] - Message sent successfully with instance: %v to: %s", instance.InstanceName, lead.Email)
+
+		// Without the key a redelivery would send the message again
+		if err := rbu.StoreSentEvent(data, lead, &instance, resp, len(communicationWhatsapp.Attachments), idempotencyKey); err != nil {
+			return err
+		}
+		sentInstance = &instance
+		rbu.trackProgress(data, models.BlastOutcomeSent)
+
+		if err := rememberLeadSender(rbu.Ctx, rbu.EventsDB, data.OrganizationID, lead.ID, sentInstance); err != nil {
+			log.Printf("Error storing lead sender: %v", err)
+		}
 
-		rbu.StoreEvent("sent", data, lead, resp)
+		if err := shortener.AttachMessageID(resp.Key.ID); err != nil {
+			log.Printf("Error attaching message id to short links: %v", err)
+		}
 
 		break
 	}
 
-	err = rbu.SendEventToBilling()
+	if sentInstance == nil {
+		if limitErr != nil {
+			return rbu.reschedule(original, lead, limitErr)
+		}
+
+		log.Printf("[BLAST] - No instance could send the message to lead: %d", lead.ID)
+		return rbu.finishMessage(models.BlastOutcomeFailed, data, lead, models.JSONB{
+			"reason":    "no instance could send the message",
+			"instances": failures,
+		})
+	}
+
+	return publishPendingBilling(rbu.Ctx, rbu.Configs, rbu.Queue, rbu.EventsDB, idempotencyKey)
+}
+
+// DeadLettered records a message the retry policy gave up on as failed, so
+// the blast progress still completes. Messages that were sent are left alone.
+func (rbu *ReceiptBlastEventUseCase) DeadLettered(event string, cause error) error {
+	var data dto.BlastEventProcess
+	if err := json.Unmarshal([]byte(event), &data); err != nil {
+		return fmt.Errorf("error decoding dead-lettered message: %v", err)
+	}
+
+	idempotencyKey := models.NewIdempotencyKey(data.OrganizationID, data.LeadID, "communication_whatsapps", strconv.Itoa(data.CommunicationWhatsappId), idempotencyTTL(rbu.Configs))
+	idempotencyKeyRepo := repositories.NewIdempotencyKeyRepository(rbu.EventsDB)
+	processed, err := idempotencyKeyRepo.Find(rbu.Ctx, idempotencyKey.Key)
 	if err != nil {
 		return err
 	}
+	if processed != nil && processed.Status == models.IdempotencyStatusSent {
+		return nil
+	}
 
-	return nil
+	leadRepo := repositories.NewLeadRepository(rbu.AfrusDB)
+	lead, err := leadRepo.FindById(rbu.Ctx, data.LeadID)
+	if err != nil {
+		log.Printf("[BLAST] - Dead-lettered message for missing lead: %d - %v", data.LeadID, err)
+		lead = &models.Lead{ID: data.LeadID, OrganizationID: data.OrganizationID}
+	}
+
+	log.Printf("[BLAST] - Message to lead: %d dead-lettered - %v", data.LeadID, cause)
+	return rbu.finishMessage(models.BlastOutcomeFailed, data, lead, models.JSONB{
+		"reason":        cause.Error(),
+		"dead_lettered": true,
+	})
+}
+
+// scheduleForOpening requeues the original event until the send window opens.
+func (rbu *ReceiptBlastEventUseCase) scheduleForOpening(original dto.BlastEventProcess, lead *models.Lead, opening time.Time, delay time.Duration) error {
+	// The message is reserved again when the window opens
+	original.PacedInstanceID = 0
+	original.NotBefore = nil
+
+	body, err := json.Marshal(original)
+	if err != nil {
+		return fmt.Errorf("error marshalling scheduled message: %v", err)
+	}
+
+	if err := rbu.Queue.Schedule(
+		rbu.Ctx,
+		rbu.Configs.EvolutionAPINotificationExchange,
+		rbu.Configs.EvolutionAPINotificationBlastRoutingKey,
+		body,
+		delay,
+	); err != nil {
+		return err
+	}
+
+	log.Printf("[BLAST] - Lead: %d is outside the send window - message scheduled for %s", lead.ID, opening.Format(time.RFC3339))
+
+	// The message is already requeued, so a failure here must not trigger a retry
+	if err := rbu.StoreEventWithDetails("scheduled", original, lead, sendWindowDetails(opening)); err != nil {
+		log.Printf("Error storing scheduled event: %v", err)
+	}
+	return fmt.Errorf("%w: outside the send window until %s", ErrMessageRescheduled, opening.Format(time.RFC3339))
+}
+
+// reschedule requeues the original event once the first rate limited
+// instance can take it again.
+func (rbu *ReceiptBlastEventUseCase) reschedule(original dto.BlastEventProcess, lead *models.Lead, limitErr *ratelimit.LimitError) error {
+	// The message is reserved again when it comes back
+	original.PacedInstanceID = 0
+	original.NotBefore = nil
+
+	body, err := json.Marshal(original)
+	if err != nil {
+		return fmt.Errorf("error marshalling rescheduled message: %v", err)
+	}
+
+	if err := rbu.Queue.Schedule(
+		rbu.Ctx,
+		rbu.Configs.EvolutionAPINotificationExchange,
+		rbu.Configs.EvolutionAPINotificationBlastRoutingKey,
+		body,
+		limitErr.RetryAfter,
+	); err != nil {
+		return err
+	}
+
+	log.Printf("[BLAST] - Message to lead: %d rescheduled in %s", lead.ID, limitErr.RetryAfter.Round(time.Second))
+	return fmt.Errorf("%w: %v", ErrMessageRescheduled, limitErr)
+}
+
+// schedulePaced requeues the original event for its slot on instance, which
+// is already reserved for it. Like reschedule, it returns
+// ErrMessageRescheduled so the delivery is acked without a retry.
+func (rbu *ReceiptBlastEventUseCase) schedulePaced(original dto.BlastEventProcess, instance *models.WhatsappInstance, lead *models.Lead, slot time.Time) error {
+	original.PacedInstanceID = int(instance.ID)
+	original.NotBefore = &slot
+
+	body, err := json.Marshal(original)
+	if err != nil {
+		return fmt.Errorf("error marshalling paced message: %v", err)
+	}
+
+	if err := rbu.Queue.Schedule(
+		rbu.Ctx,
+		rbu.Configs.EvolutionAPINotificationExchange,
+		rbu.Configs.EvolutionAPINotificationBlastRoutingKey,
+		body,
+		time.Until(slot),
+	); err != nil {
+		return err
+	}
+
+	log.Printf("[BLAST] - Message to lead: %d paced on instance: %s for %s", lead.ID, instance.InstanceName, slot.Format(time.RFC3339))
+	return fmt.Errorf("%w: paced for %s", ErrMessageRescheduled, slot.Format(time.RFC3339))
 }
 
 func (rbu *ReceiptBlastEventUseCase) sendMessage(data dto.BlastEventProcess, instance *models.WhatsappInstance, lead *models.Lead, communication *models.CommunicationWhatsapp) (*services.WhatsappResponse, error) {
@@ -120,33 +378,73 @@ func (rbu *ReceiptBlastEventUseCase) sendMessage(data dto.BlastEventProcess, ins
 	return resp, nil
 }
 
-func (rbu *ReceiptBlastEventUseCase) StoreEvent(kind string, data dto.BlastEventProcess, lead *models.Lead, resp *services.WhatsappResponse) error {
-	eventRepo := repositories.NewWhatsappEventRepository(rbu.EventsDB)
+// personalize renders the lead variables into the message content and link
+// attachments.
+func (rbu *ReceiptBlastEventUseCase) personalize(data *dto.BlastEventProcess, lead *models.Lead, communication *models.CommunicationWhatsapp) error {
+	vars := template.Variables(lead.TemplateVariables())
 
-	var messageID = ""
-	if resp != nil {
-		messageID = resp.Key.ID
+	content, err := template.Render(data.Content, vars)
+	if err != nil {
+		return err
 	}
+	data.Content = content
 
-	var eventMap models.JSONB
-	respBytes, err := json.Marshal(resp)
+	for i, attachment := range communication.Attachments {
+		if attachment.Type != uint(services.LINK) {
+			continue
+		}
+		content, err := template.Render(attachment.Content, vars)
+		if err != nil {
+			return err
+		}
+		communication.Attachments[i].Content = content
+	}
+
+	return nil
+}
+
+// tagLinks adds the communication UTM parameters to every link in the content
+// and link attachments.
+func (rbu *ReceiptBlastEventUseCase) tagLinks(data *dto.BlastEventProcess, communication *models.CommunicationWhatsapp) {
+	params := utm.NewParams(communication.GASource, communication.GAMedium, communication.GAName, communication.GAContent)
+
+	data.Content = utm.TagText(data.Content, params)
+	for i, attachment := range communication.Attachments {
+		if attachment.Type == uint(services.LINK) {
+			communication.Attachments[i].Content = utm.TagText(attachment.Content, params)
+		}
+	}
+}
+
+// shortenLinks replaces the links in the content and link attachments with
+// tracked short links.
+func (rbu *ReceiptBlastEventUseCase) shortenLinks(data *dto.BlastEventProcess, communication *models.CommunicationWhatsapp, shortener *linkShortener) error {
+	content, err := shortener.Shorten(data.Content)
 	if err != nil {
-		return fmt.Errorf("error marshalling event response: %v", err)
+		return err
 	}
-	if err := json.Unmarshal(respBytes, &eventMap); err != nil {
-		return fmt.Errorf("error unmarshalling event response: %v", err)
+	data.Content = content
+
+	for i, attachment := range communication.Attachments {
+		if attachment.Type != uint(services.LINK) {
+			continue
+		}
+		content, err := shortener.Shorten(attachment.Content)
+		if err != nil {
+			return err
+		}
+		communication.Attachments[i].Content = content
 	}
 
-	event := &models.WhatsappEvent{
-		LeadID:         data.LeadID,
-		OrganizationID: data.OrganizationID,
-		PhoneNumber:    lead.Phone,
-		ExternalID:     strconv.Itoa(data.CommunicationWhatsappId),
-		ExternalTable:  "communication_whatsapps",
-		MessageID:      messageID,
-		EventType:      1,
-		DateEvent:      time.Now().Format(time.RFC3339),
-		Event:          eventMap,
+	return nil
+}
+
+func (rbu *ReceiptBlastEventUseCase) StoreEvent(kind string, data dto.BlastEventProcess, lead *models.Lead, resp *services.WhatsappResponse) error {
+	eventRepo := repositories.NewWhatsappEventRepository(rbu.EventsDB)
+
+	event, err := rbu.newEvent(data, lead, resp)
+	if err != nil {
+		return err
 	}
 
 	if err := eventRepo.Save(rbu.Ctx, kind, event); err != nil {
@@ -158,80 +456,115 @@ func (rbu *ReceiptBlastEventUseCase) StoreEvent(kind string, data dto.BlastEvent
 	return nil
 }
 
-func (rbu *ReceiptBlastEventUseCase) SendEventToBilling() error {
-	err := rbu.Queue.Publish(rbu.Ctx, rbu.Configs.RabbitMQBillingExchange, rbu.Configs.RabbitMQBillingRoutingKey, []byte("receipt"))
+// StoreEventWithDetails records an event that has no Evolution response,
+// keeping details (e.g. the failure reason) as the event payload.
+func (rbu *ReceiptBlastEventUseCase) StoreEventWithDetails(kind string, data dto.BlastEventProcess, lead *models.Lead, details models.JSONB) error {
+	eventRepo := repositories.NewWhatsappEventRepository(rbu.EventsDB)
+
+	event, err := rbu.newEvent(data, lead, nil)
 	if err != nil {
 		return err
 	}
-	return nil
-}
+	event.Event = details
 
-func (rbu *ReceiptBlastEventUseCase) processRules(instance *models.WhatsappInstance) error {
-	if err := rbu.maxConsecutivesSent(instance); err != nil {
-		return err
-	}
-	if err := rbu.maxSentRate(instance); err != nil {
-		return err
-	}
-	if err := rbu.sleepTime(); err != nil {
-		return err
+	if err := eventRepo.Save(rbu.Ctx, kind, event); err != nil {
+		return fmt.Errorf("[EVENT] - error saving event: %v", err)
 	}
+
+	log.Printf("[EVENT] - Event of type: '%s' for communicationWhatsappId: '%d' saved successfully", kind, data.CommunicationWhatsappId)
+
 	return nil
 }
 
-func (rbu *ReceiptBlastEventUseCase) maxConsecutivesSent(instance *models.WhatsappInstance) error {
-	const (
-		baseMaxSends  = 2
-		maxSendsLimit = 6
-	)
+// StoreSentEvent records the sent event together with the idempotency key so
+// redeliveries of the same message are skipped. The key keeps the billing
+// event until publishPendingBilling publishes it.
+func (rbu *ReceiptBlastEventUseCase) StoreSentEvent(data dto.BlastEventProcess, lead *models.Lead, instance *models.WhatsappInstance, resp *services.WhatsappResponse, attachmentCount int, idempotencyKey *models.IdempotencyKey) error {
+	idempotencyKeyRepo := repositories.NewIdempotencyKeyRepository(rbu.EventsDB)
 
-	monthsSinceCreation := int(time.Since(instance.CreatedAt).Hours() / (24 * 30))
+	event, err := rbu.newEvent(data, lead, resp)
+	if err != nil {
+		return err
+	}
 
-	// Calculate max allowed sends (base + 1 per month, capped at maxSendsLimit)
-	maxAllowedSends := baseMaxSends + monthsSinceCreation
-	if maxAllowedSends > maxSendsLimit {
-		maxAllowedSends = maxSendsLimit
+	billingEvent, err := rbu.BillingEvent(data, instance, resp, attachmentCount)
+	if err != nil {
+		return err
 	}
 
-	currentSends, ok := instance.Data["consecutive_sends"].(float64)
-	if !ok {
-		instance.Data["consecutive_sends"] = baseMaxSends
-		return nil
+	idempotencyKey.MessageID = event.MessageID
+	idempotencyKey.PendingBillingEvent = string(billingEvent)
+	if err := idempotencyKeyRepo.SaveWithEvent(rbu.Ctx, idempotencyKey, "sent", event); err != nil {
+		return fmt.Errorf("[EVENT] - error saving event: %v", err)
 	}
 
-	if int(currentSends) >= maxAllowedSends {
-		return fmt.Errorf("max consecutive sends limit reached: %d/%d", int(currentSends), maxAllowedSends)
+	// Statuses that arrived before the sent event are recorded now
+	if err := replayStatusEvents(rbu.Ctx, rbu.EventsDB, event.MessageID); err != nil {
+		log.Printf("[EVENT] - Error replaying parked status events for message: %s - %v", event.MessageID, err)
 	}
 
-	instance.Data["consecutive_sends"] = currentSends + 1
+	log.Printf("[EVENT] - Event of type: 'sent' for communicationWhatsappId: '%d' saved successfully", data.CommunicationWhatsappId)
+
 	return nil
 }
 
-func (rbu *ReceiptBlastEventUseCase) maxSentRate(instance *models.WhatsappInstance) error {
-	const cooldownMinutes = 5
-
-	lastSendStr, ok := instance.Data["last_send_time"].(string)
-	if !ok {
-		instance.Data["last_send_time"] = time.Now().Format(time.RFC3339)
-		return nil
+func (rbu *ReceiptBlastEventUseCase) newEvent(data dto.BlastEventProcess, lead *models.Lead, resp *services.WhatsappResponse) (*models.WhatsappEvent, error) {
+	var messageID = ""
+	if resp != nil {
+		messageID = resp.Key.ID
 	}
 
-	lastSendTime, err := time.Parse(time.RFC3339, lastSendStr)
+	var eventMap models.JSONB
+	respBytes, err := json.Marshal(resp)
 	if err != nil {
-		return fmt.Errorf("invalid last send time format: %v", err)
+		return nil, fmt.Errorf("error marshalling event response: %v", err)
 	}
+	if err := json.Unmarshal(respBytes, &eventMap); err != nil {
+		return nil, fmt.Errorf("error unmarshalling event response: %v", err)
+	}
+
+	return &models.WhatsappEvent{
+		LeadID:         data.LeadID,
+		OrganizationID: data.OrganizationID,
+		PhoneNumber:    lead.Phone,
+		ExternalID:     strconv.Itoa(data.CommunicationWhatsappId),
+		ExternalTable:  "communication_whatsapps",
+		MessageID:      messageID,
+		EventType:      1,
+		DateEvent:      time.Now().Format(time.RFC3339),
+		Event:          eventMap,
+	}, nil
+}
 
-	// Check if enough time has passed
-	if time.Since(lastSendTime).Minutes() < cooldownMinutes {
-		return fmt.Errorf("message rate limit: the message was scheduled for %d", cooldownMinutes)
+func (rbu *ReceiptBlastEventUseCase) BillingEvent(data dto.BlastEventProcess, instance *models.WhatsappInstance, resp *services.WhatsappResponse, attachmentCount int) ([]byte, error) {
+	const sourceTable = "communication_whatsapps"
+
+	billingEvent := dto.BillingEvent{
+		Version:         dto.BillingEventVersion,
+		IdempotencyKey:  dto.BillingIdempotencyKey(sourceTable, data.CommunicationWhatsappId, data.LeadID, resp.Key.ID),
+		Channel:         dto.BillingChannel,
+		OrganizationID:  data.OrganizationID,
+		LeadID:          data.LeadID,
+		SourceTable:     sourceTable,
+		SourceID:        data.CommunicationWhatsappId,
+		MessageID:       resp.Key.ID,
+		InstanceID:      instance.ID,
+		InstanceName:    instance.InstanceName,
+		AttachmentCount: attachmentCount,
+		Timestamp:       time.Now().Format(time.RFC3339),
 	}
 
-	instance.Data["last_send_time"] = time.Now().Format(time.RFC3339)
-	return nil
+	body, err := json.Marshal(billingEvent)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling billing event: %v", err)
+	}
+	return body, nil
 }
 
-func (rbu *ReceiptBlastEventUseCase) sleepTime() error {
-	randomDelay := time.Duration(rand.Intn(60)+1) * time.Second
-	time.Sleep(randomDelay)
-	return nil
+// releaseSend gives back the warm-up slot reserved on instance for a send
+// that didn't go out, counting it as a failure when the send failed.
+func (rbu *ReceiptBlastEventUseCase) releaseSend(settings *models.OrganizationSettings, instance *models.WhatsappInstance, failed bool) {
+	if err := releaseWarmupSend(rbu.Ctx, rbu.EventsDB, settings, instance, failed); err != nil {
+		log.Printf("[BLAST] - Error releasing warm-up send of instance: %s - %v", instance.InstanceName, err)
+	}
 }
@@ -0,0 +1,37 @@
+package usecase
+
+import (
+	"afrus-whatsapp-evolution_api-notification/internal/services"
+	"afrus-whatsapp-evolution_api-notification/pkg/queue"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// ErrMessageRescheduled signals that the message was handed back to the
+// delayed exchange and the original delivery must be acknowledged without a
+// retry.
+var ErrMessageRescheduled = errors.New("message rescheduled")
+
+// ErrNotOnWhatsapp is recorded as the failure reason of messages to numbers
+// without a WhatsApp account.
+var ErrNotOnWhatsapp = errors.New("number is not on WhatsApp")
+
+// permanentIfMissing marks lookups of records that don't exist as permanent
+// failures, since retrying won't make them appear.
+func permanentIfMissing(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return queue.Permanent(err)
+	}
+	return err
+}
+
+// permanentIfRejected marks sends that Evolution API rejected as invalid as
+// permanent failures.
+func permanentIfRejected(err error) error {
+	var statusErr *services.StatusError
+	if errors.As(err, &statusErr) && statusErr.Rejected() {
+		return queue.Permanent(err)
+	}
+	return err
+}
@@ -0,0 +1,98 @@
+package usecase
+
+import (
+	config "afrus-whatsapp-evolution_api-notification/configs"
+	"afrus-whatsapp-evolution_api-notification/internal/application/repositories"
+	"afrus-whatsapp-evolution_api-notification/internal/domain/models"
+	"afrus-whatsapp-evolution_api-notification/pkg/queue"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultIdempotencyTTL = 24 * time.Hour
+	// idempotencyClaimLease bounds how long a delivery holds the claim on a
+	// message, so the claim of a consumer that died mid-send lapses.
+	idempotencyClaimLease = 10 * time.Minute
+	// claimRetryDelay is how soon a message claimed by another delivery is
+	// looked at again.
+	claimRetryDelay = 30 * time.Second
+)
+
+func idempotencyTTL(configs *config.Config) time.Duration {
+	if configs.IdempotencyTTLHours <= 0 {
+		return defaultIdempotencyTTL
+	}
+	return time.Duration(configs.IdempotencyTTLHours) * time.Hour
+}
+
+// publishPendingBilling publishes the billing event stored with the key of a
+// sent message and clears it. A message whose publish failed is retried, hits
+// its key and is billed from here, so billing is never skipped; consumers
+// dedupe on the billing idempotency key if it gets published twice.
+func publishPendingBilling(ctx context.Context, configs *config.Config, rabbitMQ *queue.RabbitMQ, eventsDB *gorm.DB, key *models.IdempotencyKey) error {
+	if key.PendingBillingEvent == "" {
+		return nil
+	}
+
+	if err := rabbitMQ.Publish(ctx, configs.RabbitMQBillingExchange, configs.RabbitMQBillingRoutingKey, []byte(key.PendingBillingEvent)); err != nil {
+		return err
+	}
+
+	idempotencyKeyRepo := repositories.NewIdempotencyKeyRepository(eventsDB)
+	if err := idempotencyKeyRepo.ClearPendingBilling(ctx, key.Key); err != nil {
+		return fmt.Errorf("error clearing pending billing event: %v", err)
+	}
+	key.PendingBillingEvent = ""
+	return nil
+}
+
+// claimMessage claims key before the message is sent, so concurrent
+// deliveries of the same message don't both send it. When the claim fails it
+// returns the stored key: either the message was already sent, or another
+// delivery holds a processing claim on it.
+func claimMessage(ctx context.Context, eventsDB *gorm.DB, key *models.IdempotencyKey) (*models.IdempotencyKey, error) {
+	idempotencyKeyRepo := repositories.NewIdempotencyKeyRepository(eventsDB)
+	claimed, err := idempotencyKeyRepo.Claim(ctx, key, idempotencyClaimLease)
+	if err != nil {
+		return nil, fmt.Errorf("error claiming idempotency key: %v", err)
+	}
+	if claimed {
+		return nil, nil
+	}
+
+	existing, err := idempotencyKeyRepo.Find(ctx, key.Key)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		// Released or expired between the claim and the lookup
+		return nil, errors.New("idempotency key changed while claiming it")
+	}
+	return existing, nil
+}
+
+// releaseMessage drops the processing claim on key when the message wasn't
+// sent, so a redelivery can send it. Sent keys are left alone.
+func releaseMessage(ctx context.Context, eventsDB *gorm.DB, key string) {
+	idempotencyKeyRepo := repositories.NewIdempotencyKeyRepository(eventsDB)
+	if err := idempotencyKeyRepo.Release(ctx, key); err != nil {
+		log.Printf("[EVENT] - Error releasing idempotency key %s: %v", key, err)
+	}
+}
+
+// requeueClaimed schedules event again while another delivery holds its
+// claim. By the time it comes back the message was either sent and is
+// skipped, or the claim was released or lapsed and it is sent.
+func requeueClaimed(ctx context.Context, configs *config.Config, rabbitMQ *queue.RabbitMQ, routingKey, event string, claim *models.IdempotencyKey) error {
+	delay := min(time.Until(claim.ExpiresAt), claimRetryDelay)
+	if err := rabbitMQ.Schedule(ctx, configs.EvolutionAPINotificationExchange, routingKey, []byte(event), delay); err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: message is being sent by another delivery", ErrMessageRescheduled)
+}
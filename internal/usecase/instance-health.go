@@ -0,0 +1,100 @@
+package usecase
+
+import (
+	config "afrus-whatsapp-evolution_api-notification/configs"
+	"afrus-whatsapp-evolution_api-notification/internal/application/repositories"
+	"afrus-whatsapp-evolution_api-notification/internal/domain/models"
+	"afrus-whatsapp-evolution_api-notification/internal/services"
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	instanceHealthTimeout     = 10 * time.Second
+	instanceHealthConcurrency = 10
+)
+
+type InstanceHealthUseCase struct {
+	Ctx                   context.Context
+	Configs               *config.Config
+	AfrusDB               *gorm.DB
+	EventsDB              *gorm.DB
+	whatsappSenderService *services.WhatsappSenderService
+}
+
+func NewInstanceHealthUseCase(ctx context.Context, configs *config.Config, afrusDB, eventsDB *gorm.DB, whatsappSenderService *services.WhatsappSenderService) *InstanceHealthUseCase {
+	return &InstanceHealthUseCase{
+		Ctx:                   ctx,
+		Configs:               configs,
+		AfrusDB:               afrusDB,
+		EventsDB:              eventsDB,
+		whatsappSenderService: whatsappSenderService,
+	}
+}
+
+// Execute checks the connection state of every instance and stores it in
+// the instance data. Instances whose check fails keep their previous state.
+func (ihu *InstanceHealthUseCase) Execute() error {
+	whatsappInstanceRepo := repositories.NewWhatsappInstanceRepository(ihu.AfrusDB)
+	instances, err := whatsappInstanceRepo.GetWhatsappInstances(ihu.Ctx)
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, instanceHealthConcurrency)
+
+	for i := range instances {
+		instance := &instances[i]
+
+		wg.Add(1)
+		semaphore <- struct{}{}
+
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			ctx, cancel := context.WithTimeout(ihu.Ctx, instanceHealthTimeout)
+			defer cancel()
+
+			state, err := ihu.whatsappSenderService.ConnectionState(ctx, instance)
+			if err != nil {
+				log.Printf("[HEALTH] - Error checking instance: %s - %v", instance.InstanceName, err)
+				return
+			}
+
+			if previous := instance.ConnectionState(); previous != state {
+				log.Printf("[HEALTH] - Instance: %s changed state from '%s' to '%s'", instance.InstanceName, previous, state)
+
+				// Losing the session while connected is the usual sign of a ban
+				if previous == models.ConnectionStateOpen && state == models.ConnectionStateClose {
+					ihu.demote(ctx, instance)
+				}
+			}
+
+			if err := whatsappInstanceRepo.UpdateConnectionState(ctx, instance.ID, state, time.Now()); err != nil {
+				log.Printf("[HEALTH] - Error storing state of instance: %s - %v", instance.InstanceName, err)
+			}
+		}()
+	}
+
+	wg.Wait()
+	return nil
+}
+
+func (ihu *InstanceHealthUseCase) demote(ctx context.Context, instance *models.WhatsappInstance) {
+	settingsRepo := repositories.NewOrganizationSettingsRepository(ihu.EventsDB)
+	settings, err := settingsRepo.FindByOrganization(ctx, int(instance.OrganizationID))
+	if err != nil {
+		log.Printf("[HEALTH] - Error loading settings of organization: %d - %v", instance.OrganizationID, err)
+		return
+	}
+
+	if err := demoteWarmup(ctx, ihu.EventsDB, settings, instance, "instance disconnected"); err != nil {
+		log.Printf("[HEALTH] - Error demoting instance: %s - %v", instance.InstanceName, err)
+	}
+}
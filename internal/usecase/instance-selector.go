@@ -0,0 +1,139 @@
+package usecase
+
+import (
+	"afrus-whatsapp-evolution_api-notification/internal/domain/models"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"math"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/exp/rand"
+)
+
+// Instance selection strategies, stored per organization in
+// OrganizationSettings.InstanceSelection.
+const (
+	InstanceSelectionOrdered           = "ordered"
+	InstanceSelectionRoundRobin        = "round_robin"
+	InstanceSelectionWeighted          = "weighted"
+	InstanceSelectionLeastRecentlyUsed = "least_recently_used"
+	InstanceSelectionStickyLead        = "sticky_lead"
+)
+
+const (
+	maxInstanceWeight    = 6
+	instanceWeightPeriod = 30 * 24 * time.Hour
+)
+
+// InstanceSelector decides the order in which the instances are tried to
+// send a message to lead. The first instance is the preferred one and the
+// rest are fallbacks.
+type InstanceSelector interface {
+	Order(instances []models.WhatsappInstance, lead *models.Lead) []models.WhatsappInstance
+}
+
+// NewInstanceSelector returns the selector for strategy, keeping the stored
+// order for empty or unknown strategies.
+func NewInstanceSelector(strategy string) InstanceSelector {
+	switch strategy {
+	case InstanceSelectionRoundRobin:
+		return roundRobinSelector{}
+	case InstanceSelectionWeighted:
+		return weightedSelector{}
+	case InstanceSelectionLeastRecentlyUsed:
+		return leastRecentlyUsedSelector{}
+	case InstanceSelectionStickyLead:
+		return stickyLeadSelector{}
+	case "", InstanceSelectionOrdered:
+		return orderedSelector{}
+	default:
+		log.Printf("[SELECTOR] - Unknown instance selection strategy '%s' - using stored order", strategy)
+		return orderedSelector{}
+	}
+}
+
+type orderedSelector struct{}
+
+func (orderedSelector) Order(instances []models.WhatsappInstance, lead *models.Lead) []models.WhatsappInstance {
+	return instances
+}
+
+// roundRobinCounters holds one counter per organization, so it stays as
+// small as the number of organizations. Blasts and triggers of the same
+// organization share the cursor, which still spreads their sends evenly.
+var roundRobinCounters sync.Map
+
+type roundRobinSelector struct{}
+
+func (roundRobinSelector) Order(instances []models.WhatsappInstance, lead *models.Lead) []models.WhatsappInstance {
+	if len(instances) < 2 {
+		return instances
+	}
+
+	counter, _ := roundRobinCounters.LoadOrStore(instances[0].OrganizationID, new(atomic.Uint64))
+	start := int((counter.(*atomic.Uint64).Add(1) - 1) % uint64(len(instances)))
+
+	return append(slices.Clone(instances[start:]), instances[:start]...)
+}
+
+// weightedSelector favors older instances, which tolerate more traffic: the
+// weight grows by one every 30 days up to maxInstanceWeight.
+type weightedSelector struct{}
+
+func (weightedSelector) Order(instances []models.WhatsappInstance, lead *models.Lead) []models.WhatsappInstance {
+	// Weighted random permutation: sorting by u^(1/w) draws every position
+	// proportionally to the remaining weights
+	keys := make(map[uint]float64, len(instances))
+	for _, instance := range instances {
+		keys[instance.ID] = math.Pow(rand.Float64(), 1/instanceWeight(&instance))
+	}
+
+	ordered := slices.Clone(instances)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return keys[ordered[i].ID] > keys[ordered[j].ID]
+	})
+	return ordered
+}
+
+func instanceWeight(instance *models.WhatsappInstance) float64 {
+	weight := 1 + int(time.Since(instance.CreatedAt)/instanceWeightPeriod)
+	if weight > maxInstanceWeight {
+		weight = maxInstanceWeight
+	}
+	return float64(weight)
+}
+
+type leastRecentlyUsedSelector struct{}
+
+func (leastRecentlyUsedSelector) Order(instances []models.WhatsappInstance, lead *models.Lead) []models.WhatsappInstance {
+	ordered := slices.Clone(instances)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].LastSendTime().Before(ordered[j].LastSendTime())
+	})
+	return ordered
+}
+
+// stickyLeadSelector ranks the instances by a hash of the lead and instance
+// (rendezvous hashing), so a lead always hears from the same number and only
+// the leads of a removed instance move to another one.
+type stickyLeadSelector struct{}
+
+func (stickyLeadSelector) Order(instances []models.WhatsappInstance, lead *models.Lead) []models.WhatsappInstance {
+	scores := make(map[uint]uint64, len(instances))
+	for _, instance := range instances {
+		hash := fnv.New64a()
+		fmt.Fprintf(hash, "%d:%d", lead.ID, instance.ID)
+		scores[instance.ID] = hash.Sum64()
+	}
+
+	ordered := slices.Clone(instances)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return scores[ordered[i].ID] > scores[ordered[j].ID]
+	})
+	return ordered
+}
@@ -0,0 +1,88 @@
+package usecase
+
+import (
+	config "afrus-whatsapp-evolution_api-notification/configs"
+	"afrus-whatsapp-evolution_api-notification/internal/application/dto"
+	"afrus-whatsapp-evolution_api-notification/internal/application/repositories"
+	"afrus-whatsapp-evolution_api-notification/internal/domain/models"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type InstanceWarmupUseCase struct {
+	Ctx      context.Context
+	Configs  *config.Config
+	AfrusDB  *gorm.DB
+	EventsDB *gorm.DB
+}
+
+func NewInstanceWarmupUseCase(ctx context.Context, configs *config.Config, afrusDB, eventsDB *gorm.DB) *InstanceWarmupUseCase {
+	return &InstanceWarmupUseCase{
+		Ctx:      ctx,
+		Configs:  configs,
+		AfrusDB:  afrusDB,
+		EventsDB: eventsDB,
+	}
+}
+
+// Report returns the daily budget and remaining quota of every instance of
+// the organization. Instances that haven't sent yet are reported with the
+// level they will start at.
+func (iwu *InstanceWarmupUseCase) Report(organizationID int) ([]dto.InstanceWarmupReport, error) {
+	whatsappInstanceRepo := repositories.NewWhatsappInstanceRepository(iwu.AfrusDB)
+	instances, err := whatsappInstanceRepo.GetWhatsappInstancesByOrganizationID(iwu.Ctx, organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	settingsRepo := repositories.NewOrganizationSettingsRepository(iwu.EventsDB)
+	settings, err := settingsRepo.FindByOrganization(iwu.Ctx, organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	warmupRepo := repositories.NewInstanceWarmupRepository(iwu.EventsDB)
+	warmups, err := warmupRepo.FindByOrganization(iwu.Ctx, organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	byInstance := make(map[uint]models.InstanceWarmup, len(warmups))
+	for _, w := range warmups {
+		byInstance[w.WhatsappInstanceID] = w
+	}
+
+	schedule := warmupSchedule(settings)
+	now := time.Now()
+
+	reports := make([]dto.InstanceWarmupReport, 0, len(instances))
+	for i := range instances {
+		instance := &instances[i]
+
+		w, ok := byInstance[instance.ID]
+		if !ok {
+			w = *newInstanceWarmup(instance, organizationID, schedule, now)
+		}
+		// Reflect a day that has not been rolled over by a send yet
+		rolloverWarmup(&w, schedule, now)
+
+		dailyBudget := schedule.DailyCap(w.Level)
+		reports = append(reports, dto.InstanceWarmupReport{
+			WhatsappInstanceID: instance.ID,
+			InstanceName:       instance.InstanceName,
+			ConnectionState:    instance.ConnectionState(),
+			Level:              w.Level,
+			MaxLevel:           schedule.MaxLevel(),
+			DailyBudget:        dailyBudget,
+			SentToday:          w.SentToday,
+			FailedToday:        w.FailedToday,
+			RemainingToday:     max(dailyBudget-w.SentToday, 0),
+			DemotedAt:          w.DemotedAt,
+			DemotionReason:     w.DemotionReason,
+		})
+	}
+
+	return reports, nil
+}
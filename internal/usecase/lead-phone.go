@@ -0,0 +1,34 @@
+package usecase
+
+import (
+	config "afrus-whatsapp-evolution_api-notification/configs"
+	"afrus-whatsapp-evolution_api-notification/internal/domain/models"
+	"afrus-whatsapp-evolution_api-notification/pkg/phone"
+)
+
+// normalizeLeadPhone rewrites lead.Phone into the international digits
+// Evolution API expects. Numbers stored without a calling code are read as
+// numbers of the organization default country.
+func normalizeLeadPhone(configs *config.Config, settings *models.OrganizationSettings, lead *models.Lead) error {
+	number, err := normalizePhone(configs, settings, lead.Phone)
+	if err != nil {
+		return err
+	}
+	lead.Phone = number
+	return nil
+}
+
+// normalizePhone returns raw as international digits, reading numbers without
+// a calling code as numbers of the organization default country.
+func normalizePhone(configs *config.Config, settings *models.OrganizationSettings, raw string) (string, error) {
+	defaultCountry := settings.DefaultCountry
+	if defaultCountry == "" {
+		defaultCountry = configs.DefaultPhoneCountry
+	}
+
+	number, err := phone.Parse(raw, defaultCountry)
+	if err != nil {
+		return "", err
+	}
+	return number.Digits(), nil
+}
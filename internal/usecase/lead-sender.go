@@ -0,0 +1,50 @@
+package usecase
+
+import (
+	"afrus-whatsapp-evolution_api-notification/internal/application/repositories"
+	"afrus-whatsapp-evolution_api-notification/internal/domain/models"
+	"context"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// preferLeadSender moves the instance that last messaged the lead to the
+// front of instances while it is connected, keeping the rest as fallbacks.
+func preferLeadSender(ctx context.Context, eventsDB *gorm.DB, organizationID int, lead *models.Lead, instances []models.WhatsappInstance) ([]models.WhatsappInstance, error) {
+	leadSenderRepo := repositories.NewLeadSenderRepository(eventsDB)
+	leadSender, err := leadSenderRepo.Find(ctx, organizationID, lead.ID)
+	if err != nil || leadSender == nil {
+		return instances, err
+	}
+
+	return preferInstance(instances, leadSender.WhatsappInstanceID), nil
+}
+
+// preferInstance moves the instance with id to the front of instances while
+// it is connected, keeping the rest in order as fallbacks.
+func preferInstance(instances []models.WhatsappInstance, id uint) []models.WhatsappInstance {
+	index := slices.IndexFunc(instances, func(instance models.WhatsappInstance) bool {
+		return instance.ID == id
+	})
+	if index <= 0 || !instances[index].IsConnected() {
+		return instances
+	}
+
+	ordered := make([]models.WhatsappInstance, 0, len(instances))
+	ordered = append(ordered, instances[index])
+	ordered = append(ordered, instances[:index]...)
+	return append(ordered, instances[index+1:]...)
+}
+
+// rememberLeadSender records the instance that just messaged the lead.
+func rememberLeadSender(ctx context.Context, eventsDB *gorm.DB, organizationID, leadID int, instance *models.WhatsappInstance) error {
+	leadSenderRepo := repositories.NewLeadSenderRepository(eventsDB)
+	return leadSenderRepo.Save(ctx, &models.LeadSender{
+		OrganizationID:     organizationID,
+		LeadID:             leadID,
+		WhatsappInstanceID: instance.ID,
+		UpdatedAt:          time.Now(),
+	})
+}
@@ -0,0 +1,175 @@
+package usecase
+
+import (
+	config "afrus-whatsapp-evolution_api-notification/configs"
+	"afrus-whatsapp-evolution_api-notification/internal/application/repositories"
+	"afrus-whatsapp-evolution_api-notification/internal/domain/models"
+	"afrus-whatsapp-evolution_api-notification/pkg/pacing"
+	"afrus-whatsapp-evolution_api-notification/pkg/ratelimit"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// pacingTolerance is how early a paced message may be sent.
+	pacingTolerance = time.Second
+	// maxPacingHorizon caps how far ahead an instance hands out slots, so a
+	// busy instance rejects messages instead of queueing them for hours.
+	maxPacingHorizon = 30 * time.Minute
+	defaultMinGap    = time.Second
+	defaultMaxGap    = time.Minute
+)
+
+// defaultRateLimitPolicy keeps the historical pace of one message every five
+// minutes per instance for organizations without a policy.
+var defaultRateLimitPolicy = ratelimit.Policy{
+	BucketCapacity: 1,
+	RefillInterval: 5 * time.Minute,
+}
+
+// reserveInstanceSend checks the instance warm-up budget and rate limit
+// policy and records the send under their row locks. It returns the paced
+// slot at which the message should be sent; a *ratelimit.LimitError means
+// the instance can't take the message yet.
+//
+// The warm-up lives in the events database and the rate limit state in the
+// Afrus one, so the two reservations are separate transactions and not
+// atomic. The rate limit is reserved inside the warm-up transaction: a send
+// rejected by the warm-up cap never reaches the rate limit, and one rejected
+// by the rate limit rolls the warm-up back. Only when the warm-up fails to
+// commit after the rate limit did is the rate limit slot consumed without a
+// send, which errs on the side of sending less and is logged. Callers give
+// the warm-up slot back with releaseWarmupSend when the send doesn't go out.
+func reserveInstanceSend(ctx context.Context, configs *config.Config, afrusDB, eventsDB *gorm.DB, settings *models.OrganizationSettings, instance *models.WhatsappInstance) (time.Time, error) {
+	policyRepo := repositories.NewRateLimitPolicyRepository(eventsDB)
+	storedPolicy, err := policyRepo.FindForInstance(ctx, settings.OrganizationID, instance.ID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	policy := defaultRateLimitPolicy
+	if storedPolicy != nil {
+		policy = storedPolicy.Policy()
+	}
+	pacer := instancePacer(configs)
+
+	var slot time.Time
+	var rateLimitReserved bool
+	whatsappInstanceRepo := repositories.NewWhatsappInstanceRepository(afrusDB)
+	err = reserveWarmupSend(ctx, configs, eventsDB, settings, instance, func() error {
+		reserved, err := whatsappInstanceRepo.ReserveSend(ctx, instance.ID, func(locked *models.WhatsappInstance) error {
+			var err error
+			slot, err = allowSend(configs, policy, pacer, locked, time.Now())
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		rateLimitReserved = true
+		instance.Data = reserved.Data
+		return nil
+	})
+	if err != nil && rateLimitReserved {
+		log.Printf("[RATE LIMIT] - Instance: %d rate limit slot consumed without recording the warm-up send: %v", instance.ID, err)
+	}
+	return slot, err
+}
+
+// duePacedSlot returns the slot reserved by a paced message while it is due,
+// within pacingTolerance either way. A message that comes back far from its
+// slot, early or late, holds a stale reservation and reserves its send again.
+func duePacedSlot(notBefore *time.Time) (time.Time, bool) {
+	if notBefore == nil {
+		return time.Time{}, false
+	}
+	if wait := time.Until(*notBefore); wait > pacingTolerance || wait < -pacingTolerance {
+		return time.Time{}, false
+	}
+	return *notBefore, true
+}
+
+// pacedFields are the keys of the paced reservation in the blast and
+// autoresponder messages.
+var pacedFields = []string{"notBefore", "pacedInstanceId", "not_before"}
+
+// WithoutPacing drops the paced reservation from a message, so a retried
+// message reserves its send again. Bodies that aren't JSON objects are
+// returned as they are.
+func WithoutPacing(body []byte) []byte {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return body
+	}
+	for _, field := range pacedFields {
+		delete(fields, field)
+	}
+
+	stripped, err := json.Marshal(fields)
+	if err != nil {
+		return body
+	}
+	return stripped
+}
+
+// allowSend claims the next paced slot of the instance and applies policy at
+// that time to the rate limit state kept in the instance data. Limits are not
+// enforced in development.
+func allowSend(configs *config.Config, policy ratelimit.Policy, pacer pacing.Pacer, instance *models.WhatsappInstance, now time.Time) (time.Time, error) {
+	var state ratelimit.State
+	if stored, ok := instance.Data["rate_limit"]; ok {
+		if err := convertJSON(stored, &state); err != nil {
+			return time.Time{}, fmt.Errorf("invalid rate limit state: %v", err)
+		}
+	}
+
+	nextSlot, _ := instance.Data["next_slot"].(string)
+	nextFree, _ := time.Parse(time.RFC3339Nano, nextSlot)
+	slot, next := pacer.Claim(nextFree, now)
+	if wait := slot.Sub(now); wait > maxPacingHorizon {
+		return time.Time{}, &ratelimit.LimitError{Limit: "pacing", RetryAfter: wait}
+	}
+
+	if configs.Environment != "development" {
+		if err := policy.Allow(&state, slot); err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	var stored models.JSONB
+	if err := convertJSON(state, &stored); err != nil {
+		return time.Time{}, fmt.Errorf("error encoding rate limit state: %v", err)
+	}
+	instance.Data["rate_limit"] = stored
+	instance.Data["next_slot"] = next.Format(time.RFC3339Nano)
+	instance.Data["last_send_time"] = slot.Format(time.RFC3339)
+	// Counter of the former limits, which never reset
+	delete(instance.Data, "consecutive_sends")
+
+	return slot, nil
+}
+
+func convertJSON(from, to interface{}) error {
+	bytes, err := json.Marshal(from)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(bytes, to)
+}
+
+// instancePacer spaces the sends of an instance by a random gap, 1 to 60
+// seconds unless configured otherwise.
+func instancePacer(configs *config.Config) pacing.Pacer {
+	if configs.PacingMinGapSeconds <= 0 && configs.PacingMaxGapSeconds <= 0 {
+		return pacing.Pacer{MinGap: defaultMinGap, MaxGap: defaultMaxGap}
+	}
+	return pacing.Pacer{
+		MinGap: time.Duration(configs.PacingMinGapSeconds) * time.Second,
+		MaxGap: time.Duration(configs.PacingMaxGapSeconds) * time.Second,
+	}
+}
@@ -0,0 +1,216 @@
+package usecase
+
+import (
+	config "afrus-whatsapp-evolution_api-notification/configs"
+	"afrus-whatsapp-evolution_api-notification/internal/application/repositories"
+	"afrus-whatsapp-evolution_api-notification/internal/domain/models"
+	"afrus-whatsapp-evolution_api-notification/pkg/pacing"
+	"afrus-whatsapp-evolution_api-notification/pkg/ratelimit"
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const concurrentSenders = 20
+
+var limitPolicies = []struct {
+	name   string
+	policy ratelimit.Policy
+	want   int
+}{
+	{name: "token bucket", policy: ratelimit.Policy{BucketCapacity: 5, RefillInterval: 24 * time.Hour}, want: 5},
+	{name: "per minute", policy: ratelimit.Policy{BucketCapacity: 100, RefillInterval: time.Hour, PerMinute: 3}, want: 3},
+	{name: "per hour", policy: ratelimit.Policy{PerHour: 4}, want: 4},
+}
+
+// sendConcurrently runs concurrentSenders goroutines through send and
+// returns how many were accepted. Any error other than a limit fails the test.
+func sendConcurrently(t *testing.T, send func() error) int {
+	t.Helper()
+
+	var accepted atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < concurrentSenders; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := send()
+			var limitErr *ratelimit.LimitError
+			switch {
+			case err == nil:
+				accepted.Add(1)
+			case !errors.As(err, &limitErr):
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	return int(accepted.Load())
+}
+
+func TestAllowSendConcurrent(t *testing.T) {
+	configs := &config.Config{Environment: "production"}
+	// A fixed clock keeps every send inside the same windows
+	now := time.Date(2026, 3, 10, 12, 0, 30, 0, time.UTC)
+
+	for _, tt := range limitPolicies {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := &models.WhatsappInstance{ID: 1, Data: models.JSONB{}}
+			// Stands in for the row lock taken by ReserveSend
+			var rowLock sync.Mutex
+
+			accepted := sendConcurrently(t, func() error {
+				rowLock.Lock()
+				defer rowLock.Unlock()
+				_, err := allowSend(configs, tt.policy, pacing.Pacer{}, instance, now)
+				return err
+			})
+			if accepted != tt.want {
+				t.Errorf("accepted %d sends, want %d", accepted, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllowSendDevelopmentSkipsLimits(t *testing.T) {
+	configs := &config.Config{Environment: "development"}
+	instance := &models.WhatsappInstance{ID: 1, Data: models.JSONB{}}
+	now := time.Now()
+
+	for i := 0; i < 3; i++ {
+		if _, err := allowSend(configs, ratelimit.Policy{BucketCapacity: 1, RefillInterval: time.Hour}, pacing.Pacer{}, instance, now); err != nil {
+			t.Fatalf("send %d: unexpected error: %v", i, err)
+		}
+	}
+}
+
+func TestAllowSendPacingHorizon(t *testing.T) {
+	configs := &config.Config{Environment: "production"}
+	now := time.Now()
+	instance := &models.WhatsappInstance{ID: 1, Data: models.JSONB{
+		"next_slot": now.Add(maxPacingHorizon + time.Minute).Format(time.RFC3339Nano),
+	}}
+
+	_, err := allowSend(configs, ratelimit.Policy{}, pacing.Pacer{}, instance, now)
+	var limitErr *ratelimit.LimitError
+	if !errors.As(err, &limitErr) || limitErr.Limit != "pacing" {
+		t.Fatalf("got %v, want a pacing limit error", err)
+	}
+	if _, ok := instance.Data["rate_limit"]; ok {
+		t.Error("rejected send updated the rate limit state")
+	}
+}
+
+func TestDuePacedSlot(t *testing.T) {
+	now := time.Now()
+	at := func(offset time.Duration) *time.Time {
+		slot := now.Add(offset)
+		return &slot
+	}
+
+	tests := []struct {
+		name      string
+		notBefore *time.Time
+		want      bool
+	}{
+		{name: "not paced", notBefore: nil, want: false},
+		{name: "due now", notBefore: at(0), want: true},
+		{name: "slightly early", notBefore: at(pacingTolerance / 2), want: true},
+		{name: "slightly late", notBefore: at(-pacingTolerance / 2), want: true},
+		{name: "too early", notBefore: at(time.Minute), want: false},
+		{name: "stale after a retry", notBefore: at(-time.Minute), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slot, due := duePacedSlot(tt.notBefore)
+			if due != tt.want {
+				t.Fatalf("due = %v, want %v", due, tt.want)
+			}
+			if due && !slot.Equal(*tt.notBefore) {
+				t.Errorf("slot = %s, want %s", slot, tt.notBefore)
+			}
+		})
+	}
+}
+
+func TestWithoutPacing(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{name: "blast", body: `{"leadId":1,"notBefore":"2026-01-01T00:00:00Z","pacedInstanceId":7}`, want: `{"leadId":1}`},
+		{name: "autoresponder", body: `{"lead_id":1,"not_before":"2026-01-01T00:00:00Z","whatsapp_instance_id":7}`, want: `{"lead_id":1,"whatsapp_instance_id":7}`},
+		{name: "not paced", body: `{"leadId":1}`, want: `{"leadId":1}`},
+		{name: "not JSON", body: `oops`, want: `oops`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(WithoutPacing([]byte(tt.body))); got != tt.want {
+				t.Errorf("WithoutPacing(%s) = %s, want %s", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestReserveSendConcurrent runs the same policies against a real row lock.
+// TEST_DATABASE_DSN must point at a scratch PostgreSQL database.
+func TestReserveSendConcurrent(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("error opening database: %v", err)
+	}
+	err = db.Exec(`CREATE TABLE IF NOT EXISTS whatsapp_instances (
+		id serial PRIMARY KEY,
+		"instanceName" text,
+		"instanceId" text,
+		owner text,
+		data jsonb,
+		organization_id int,
+		created_at timestamp,
+		updated_at timestamp
+	)`).Error
+	if err != nil {
+		t.Fatalf("error creating whatsapp_instances: %v", err)
+	}
+
+	configs := &config.Config{Environment: "production"}
+	now := time.Date(2026, 3, 10, 12, 0, 30, 0, time.UTC)
+	repo := repositories.NewWhatsappInstanceRepository(db)
+
+	for _, tt := range limitPolicies {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := models.WhatsappInstance{InstanceName: "rate-limit-test", Data: models.JSONB{}}
+			if err := db.Create(&instance).Error; err != nil {
+				t.Fatalf("error creating instance: %v", err)
+			}
+			t.Cleanup(func() { db.Delete(&models.WhatsappInstance{}, instance.ID) })
+
+			accepted := sendConcurrently(t, func() error {
+				_, err := repo.ReserveSend(context.Background(), instance.ID, func(locked *models.WhatsappInstance) error {
+					_, err := allowSend(configs, tt.policy, pacing.Pacer{}, locked, now)
+					return err
+				})
+				return err
+			})
+			if accepted != tt.want {
+				t.Errorf("accepted %d sends, want %d", accepted, tt.want)
+			}
+		})
+	}
+}
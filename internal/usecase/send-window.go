@@ -0,0 +1,61 @@
+package usecase
+
+import (
+	config "afrus-whatsapp-evolution_api-notification/configs"
+	"afrus-whatsapp-evolution_api-notification/internal/domain/models"
+	"afrus-whatsapp-evolution_api-notification/pkg/sendwindow"
+	"log"
+	"time"
+
+	"golang.org/x/exp/rand"
+)
+
+// maxSendWindowJitter spreads the messages rescheduled to the same opening so
+// they don't all reach the queue at once.
+const maxSendWindowJitter = 15 * time.Minute
+
+// nextSendOpening returns when the message to lead may be sent according to
+// the organization send window, evaluated in the lead's timezone. It returns
+// the current time when the window is open.
+func nextSendOpening(configs *config.Config, settings *models.OrganizationSettings, lead *models.Lead) time.Time {
+	start, end := settings.SendWindowStart, settings.SendWindowEnd
+	if start == "" && end == "" {
+		start, end = configs.SendWindowStart, configs.SendWindowEnd
+	}
+	window, err := sendwindow.Parse(start, end)
+	if err != nil {
+		// A misconfigured window shouldn't hold the organization's messages back
+		log.Printf("[SEND WINDOW] - Ignoring send window for organization: %d - %v", settings.OrganizationID, err)
+		window = sendwindow.Window{}
+	}
+
+	fallbackTimezone := settings.Timezone
+	if fallbackTimezone == "" {
+		fallbackTimezone = configs.DefaultTimezone
+	}
+	location := sendwindow.ResolveLocation(lead.Timezone, lead.Phone, fallbackTimezone)
+
+	now := time.Now().In(location)
+	opening := window.NextOpening(now)
+	if opening.Equal(now) {
+		return now
+	}
+
+	// Short windows must not be jittered past their end
+	jitter := maxSendWindowJitter
+	if open := window.Closing(opening).Sub(opening); open < jitter {
+		jitter = open
+	}
+	if jitter <= 0 {
+		return opening
+	}
+	return opening.Add(time.Duration(rand.Int63n(int64(jitter))))
+}
+
+func sendWindowDetails(opening time.Time) models.JSONB {
+	return models.JSONB{
+		"reason":        "outside send window",
+		"scheduled_for": opening.Format(time.RFC3339),
+		"timezone":      opening.Location().String(),
+	}
+}
@@ -0,0 +1,56 @@
+package usecase
+
+import (
+	config "afrus-whatsapp-evolution_api-notification/configs"
+	"afrus-whatsapp-evolution_api-notification/internal/application/repositories"
+	"afrus-whatsapp-evolution_api-notification/internal/domain/models"
+	"context"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ShortLinkClickUseCase struct {
+	Ctx      context.Context
+	Configs  *config.Config
+	EventsDB *gorm.DB
+}
+
+func NewShortLinkClickUseCase(ctx context.Context, configs *config.Config, eventsDB *gorm.DB) *ShortLinkClickUseCase {
+	return &ShortLinkClickUseCase{
+		Ctx:      ctx,
+		Configs:  configs,
+		EventsDB: eventsDB,
+	}
+}
+
+// Execute records a click on the short link identified by code and returns
+// the original URL to redirect to. A click that cannot be stored is logged
+// but never blocks the redirect.
+func (slc *ShortLinkClickUseCase) Execute(code, userAgent, ipAddress string) (string, error) {
+	shortLinkRepo := repositories.NewShortLinkRepository(slc.EventsDB)
+
+	shortLink, err := shortLinkRepo.FindByCode(slc.Ctx, code)
+	if err != nil {
+		return "", err
+	}
+
+	click := &models.LinkClick{
+		ShortLinkID:    shortLink.ID,
+		OrganizationID: shortLink.OrganizationID,
+		LeadID:         shortLink.LeadID,
+		ExternalID:     shortLink.ExternalID,
+		ExternalTable:  shortLink.ExternalTable,
+		MessageID:      shortLink.MessageID,
+		UserAgent:      userAgent,
+		IPAddress:      ipAddress,
+		ClickedAt:      time.Now(),
+	}
+
+	if err := shortLinkRepo.SaveClick(slc.Ctx, click); err != nil {
+		log.Printf("[SHORT LINK] - Error saving click for code %s: %v", code, err)
+	}
+
+	return shortLink.URL, nil
+}
@@ -0,0 +1,71 @@
+package usecase
+
+import (
+	config "afrus-whatsapp-evolution_api-notification/configs"
+	"afrus-whatsapp-evolution_api-notification/internal/application/repositories"
+	"afrus-whatsapp-evolution_api-notification/internal/domain/models"
+	"afrus-whatsapp-evolution_api-notification/pkg/shortlink"
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// linkShortener swaps the links of a single outgoing message for tracked
+// short links and remembers their codes so the message id can be attached
+// once Evolution has accepted the message.
+type linkShortener struct {
+	ctx           context.Context
+	configs       *config.Config
+	repo          *repositories.ShortLinkRepository
+	lead          *models.Lead
+	externalTable string
+	externalID    string
+	codes         []string
+}
+
+func newLinkShortener(ctx context.Context, configs *config.Config, eventsDB *gorm.DB, lead *models.Lead, externalTable, externalID string) *linkShortener {
+	return &linkShortener{
+		ctx:           ctx,
+		configs:       configs,
+		repo:          repositories.NewShortLinkRepository(eventsDB),
+		lead:          lead,
+		externalTable: externalTable,
+		externalID:    externalID,
+	}
+}
+
+// Shorten returns text unchanged when no short link base URL is configured.
+func (ls *linkShortener) Shorten(text string) (string, error) {
+	if ls.configs.ShortLinkBaseURL == "" {
+		return text, nil
+	}
+
+	return shortlink.Shorten(text, ls.configs.ShortLinkBaseURL, func(link string) (string, error) {
+		code, err := shortlink.NewCode()
+		if err != nil {
+			return "", err
+		}
+
+		shortLink := &models.ShortLink{
+			Code:           code,
+			URL:            link,
+			OrganizationID: ls.lead.OrganizationID,
+			LeadID:         ls.lead.ID,
+			ExternalID:     ls.externalID,
+			ExternalTable:  ls.externalTable,
+			CreatedAt:      time.Now(),
+		}
+		if err := ls.repo.Create(ls.ctx, shortLink); err != nil {
+			return "", fmt.Errorf("error saving short link: %v", err)
+		}
+
+		ls.codes = append(ls.codes, code)
+		return code, nil
+	})
+}
+
+func (ls *linkShortener) AttachMessageID(messageID string) error {
+	return ls.repo.AttachMessageID(ls.ctx, ls.codes, messageID)
+}
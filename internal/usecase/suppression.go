@@ -0,0 +1,126 @@
+package usecase
+
+import (
+	config "afrus-whatsapp-evolution_api-notification/configs"
+	"afrus-whatsapp-evolution_api-notification/internal/application/dto"
+	"afrus-whatsapp-evolution_api-notification/internal/application/repositories"
+	"afrus-whatsapp-evolution_api-notification/internal/domain/models"
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+var ErrInvalidSuppression = errors.New("invalid suppression")
+
+type SuppressionUseCase struct {
+	Ctx      context.Context
+	Configs  *config.Config
+	EventsDB *gorm.DB
+}
+
+func NewSuppressionUseCase(ctx context.Context, configs *config.Config, eventsDB *gorm.DB) *SuppressionUseCase {
+	return &SuppressionUseCase{
+		Ctx:      ctx,
+		Configs:  configs,
+		EventsDB: eventsDB,
+	}
+}
+
+// List returns the entries of the organization, only those of rawPhone when
+// it's given.
+func (su *SuppressionUseCase) List(organizationID int, rawPhone string) ([]models.Suppression, error) {
+	suppressionRepo := repositories.NewSuppressionRepository(su.EventsDB)
+	if rawPhone == "" {
+		return suppressionRepo.FindByOrganization(su.Ctx, organizationID)
+	}
+
+	settingsRepo := repositories.NewOrganizationSettingsRepository(su.EventsDB)
+	settings, err := settingsRepo.FindByOrganization(su.Ctx, organizationID)
+	if err != nil {
+		return nil, err
+	}
+	return suppressionRepo.FindByPhone(su.Ctx, organizationID, suppressionPhone(su.Configs, settings, rawPhone))
+}
+
+func (su *SuppressionUseCase) Add(request dto.SuppressionRequest, source string) (*models.Suppression, error) {
+	if request.OrganizationID <= 0 {
+		return nil, errors.Join(ErrInvalidSuppression, errors.New("organizationId is required"))
+	}
+	if request.LeadID == nil && request.Phone == nil && request.Scope != "organization" {
+		return nil, errors.Join(ErrInvalidSuppression, errors.New(`leadId or phone is required, or scope "organization" to suppress the whole organization`))
+	}
+	if (request.LeadID != nil || request.Phone != nil) && request.Scope == "organization" {
+		return nil, errors.Join(ErrInvalidSuppression, errors.New(`scope "organization" doesn't take a leadId or phone`))
+	}
+
+	suppression := &models.Suppression{
+		OrganizationID: request.OrganizationID,
+		LeadID:         request.LeadID,
+		Reason:         request.Reason,
+		Source:         source,
+		CreatedAt:      time.Now(),
+	}
+
+	if request.Phone != nil {
+		settingsRepo := repositories.NewOrganizationSettingsRepository(su.EventsDB)
+		settings, err := settingsRepo.FindByOrganization(su.Ctx, request.OrganizationID)
+		if err != nil {
+			return nil, err
+		}
+
+		phone := suppressionPhone(su.Configs, settings, *request.Phone)
+		if phone == "" {
+			return nil, errors.Join(ErrInvalidSuppression, errors.New("phone has no digits"))
+		}
+		suppression.Phone = &phone
+	}
+
+	suppressionRepo := repositories.NewSuppressionRepository(su.EventsDB)
+	if err := suppressionRepo.Create(su.Ctx, suppression); err != nil {
+		return nil, err
+	}
+	return suppression, nil
+}
+
+func (su *SuppressionUseCase) Remove(organizationID, id int) error {
+	suppressionRepo := repositories.NewSuppressionRepository(su.EventsDB)
+	return suppressionRepo.Delete(su.Ctx, organizationID, id)
+}
+
+// findSuppression returns the entry blocking sends to lead, if any. The lead
+// phone must already be normalized by normalizeLeadPhone.
+func findSuppression(ctx context.Context, eventsDB *gorm.DB, organizationID int, lead *models.Lead) (*models.Suppression, error) {
+	suppressionRepo := repositories.NewSuppressionRepository(eventsDB)
+	return suppressionRepo.FindMatch(ctx, organizationID, lead.ID, lead.Phone)
+}
+
+// suppressionPhone normalizes raw like lead phones are, with the organization
+// default country, so entries match however the number was typed. Numbers
+// that can't be parsed keep only their digits.
+func suppressionPhone(configs *config.Config, settings *models.OrganizationSettings, raw string) string {
+	if number, err := normalizePhone(configs, settings, raw); err == nil {
+		return number
+	}
+	return phoneDigits(raw)
+}
+
+func phoneDigits(raw string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, raw)
+}
+
+func suppressionDetails(suppression *models.Suppression) models.JSONB {
+	return models.JSONB{
+		"reason":         suppression.Reason,
+		"scope":          suppression.Scope(),
+		"suppression_id": suppression.ID,
+	}
+}
@@ -0,0 +1,81 @@
+package usecase
+
+import (
+	config "afrus-whatsapp-evolution_api-notification/configs"
+	"afrus-whatsapp-evolution_api-notification/internal/application/dto"
+	"afrus-whatsapp-evolution_api-notification/internal/domain/models"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSuppressionPhoneMatchesLeadPhone(t *testing.T) {
+	configs := &config.Config{DefaultPhoneCountry: "BR"}
+
+	tests := []struct {
+		name      string
+		settings  *models.OrganizationSettings
+		raw       string
+		leadPhone string
+	}{
+		{name: "domestic number", settings: &models.OrganizationSettings{}, raw: "(11) 98765-4321", leadPhone: "11987654321"},
+		{name: "international number", settings: &models.OrganizationSettings{}, raw: "+55 11 98765-4321", leadPhone: "011 98765-4321"},
+		{name: "organization country", settings: &models.OrganizationSettings{DefaultCountry: "AR"}, raw: "011 15 1234-5678", leadPhone: "+54 9 11 1234-5678"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lead := &models.Lead{Phone: tt.leadPhone}
+			if err := normalizeLeadPhone(configs, tt.settings, lead); err != nil {
+				t.Fatalf("error normalizing lead phone: %v", err)
+			}
+			if got := suppressionPhone(configs, tt.settings, tt.raw); got != lead.Phone {
+				t.Errorf("suppressionPhone(%q) = %s, want the lead phone %s", tt.raw, got, lead.Phone)
+			}
+		})
+	}
+}
+
+func TestSuppressionPhoneKeepsDigitsOfInvalidNumbers(t *testing.T) {
+	configs := &config.Config{DefaultPhoneCountry: "BR"}
+	if got := suppressionPhone(configs, &models.OrganizationSettings{}, "12-34"); got != "1234" {
+		t.Errorf("suppressionPhone = %s, want 1234", got)
+	}
+}
+
+func TestSuppressionPhoneOfOptOutJID(t *testing.T) {
+	configs := &config.Config{DefaultPhoneCountry: "BR"}
+	settings := &models.OrganizationSettings{}
+
+	// A foreign number must not be read as a number of the default country
+	if got := suppressionPhone(configs, settings, "+447911123456"); got != "447911123456" {
+		t.Errorf("suppressionPhone = %s, want 447911123456", got)
+	}
+	if got, want := suppressionPhone(configs, settings, "+5511987654321"), suppressionPhone(configs, settings, "(11) 98765-4321"); got != want {
+		t.Errorf("opt-out phone %s doesn't match domestic entry %s", got, want)
+	}
+}
+
+func TestAddRejectsInvalidSuppressions(t *testing.T) {
+	leadID, phone := 10, "11987654321"
+
+	tests := []struct {
+		name    string
+		request dto.SuppressionRequest
+	}{
+		{name: "missing organization", request: dto.SuppressionRequest{LeadID: &leadID}},
+		{name: "no target", request: dto.SuppressionRequest{OrganizationID: 1}},
+		{name: "misspelled scope", request: dto.SuppressionRequest{OrganizationID: 1, Scope: "organisation"}},
+		{name: "organization scope with lead", request: dto.SuppressionRequest{OrganizationID: 1, LeadID: &leadID, Scope: "organization"}},
+		{name: "organization scope with phone", request: dto.SuppressionRequest{OrganizationID: 1, Phone: &phone, Scope: "organization"}},
+	}
+
+	su := NewSuppressionUseCase(context.Background(), &config.Config{}, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := su.Add(tt.request, models.SuppressionSourceAPI); !errors.Is(err, ErrInvalidSuppression) {
+				t.Errorf("got %v, want ErrInvalidSuppression", err)
+			}
+		})
+	}
+}
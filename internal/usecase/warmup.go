@@ -0,0 +1,155 @@
+package usecase
+
+import (
+	config "afrus-whatsapp-evolution_api-notification/configs"
+	"afrus-whatsapp-evolution_api-notification/internal/application/repositories"
+	"afrus-whatsapp-evolution_api-notification/internal/domain/models"
+	"afrus-whatsapp-evolution_api-notification/pkg/ratelimit"
+	"afrus-whatsapp-evolution_api-notification/pkg/warmup"
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// warmupSchedule returns the organization warm-up schedule, falling back to
+// the default one when it's not set or invalid.
+func warmupSchedule(settings *models.OrganizationSettings) warmup.Schedule {
+	schedule, err := warmup.ParseSchedule(settings.WarmupSchedule)
+	if err != nil {
+		log.Printf("[WARMUP] - Ignoring warm-up schedule of organization: %d - %v", settings.OrganizationID, err)
+		return warmup.DefaultSchedule
+	}
+	return schedule
+}
+
+// newInstanceWarmup starts tracking instance at the level matching its age,
+// so instances that have been sending for a while aren't sent back to the
+// first day of the schedule.
+func newInstanceWarmup(instance *models.WhatsappInstance, organizationID int, schedule warmup.Schedule, now time.Time) *models.InstanceWarmup {
+	days := int(now.Sub(instance.CreatedAt) / (24 * time.Hour))
+	return &models.InstanceWarmup{
+		WhatsappInstanceID: instance.ID,
+		OrganizationID:     organizationID,
+		Level:              schedule.Clamp(days),
+		Day:                now.UTC().Format(time.DateOnly),
+		StartedAt:          now,
+		UpdatedAt:          now,
+	}
+}
+
+// rolloverWarmup starts a new day, promoting the instance after a healthy
+// day of sending or demoting it after an unhealthy one.
+func rolloverWarmup(w *models.InstanceWarmup, schedule warmup.Schedule, now time.Time) {
+	today := now.UTC().Format(time.DateOnly)
+	if w.Day == today {
+		return
+	}
+
+	// Failed sends give their slot back, so the attempts of the day are the
+	// sent and the failed ones
+	if attempts := w.SentToday + w.FailedToday; attempts > 0 && !w.DemotedOn(w.Day) {
+		if warmup.Unhealthy(attempts, w.FailedToday) {
+			demote(w, fmt.Sprintf("failure rate %d/%d on %s", w.FailedToday, attempts, w.Day), now)
+		} else {
+			w.Level = schedule.Clamp(w.Level + 1)
+		}
+	}
+
+	w.Day = today
+	w.SentToday = 0
+	w.FailedToday = 0
+}
+
+func demote(w *models.InstanceWarmup, reason string, now time.Time) {
+	w.Level = warmup.Demote(w.Level)
+	w.DemotedAt = &now
+	w.DemotionReason = reason
+	log.Printf("[WARMUP] - Instance: %d demoted to level %d - %s", w.WhatsappInstanceID, w.Level, reason)
+}
+
+// reserveWarmupSend runs reserve within the instance daily warm-up budget and
+// counts the send when it succeeds. The budget is not enforced in
+// development.
+func reserveWarmupSend(ctx context.Context, configs *config.Config, eventsDB *gorm.DB, settings *models.OrganizationSettings, instance *models.WhatsappInstance, reserve func() error) error {
+	schedule := warmupSchedule(settings)
+	now := time.Now()
+
+	warmupRepo := repositories.NewInstanceWarmupRepository(eventsDB)
+	_, err := warmupRepo.Update(ctx, newInstanceWarmup(instance, settings.OrganizationID, schedule, now), func(w *models.InstanceWarmup) error {
+		rolloverWarmup(w, schedule, now)
+
+		if dailyCap := schedule.DailyCap(w.Level); w.SentToday >= dailyCap && configs.Environment != "development" {
+			nextDay := now.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+			return &ratelimit.LimitError{
+				Limit:      fmt.Sprintf("warm-up daily (%d)", dailyCap),
+				RetryAfter: nextDay.Sub(now),
+			}
+		}
+
+		if err := reserve(); err != nil {
+			return err
+		}
+
+		w.SentToday++
+		w.UpdatedAt = now
+		return nil
+	})
+	return err
+}
+
+// recordWarmupFailure counts a failed send that still used its warm-up
+// slot, demoting the instance as soon as its failure rate for the day becomes
+// unhealthy.
+func recordWarmupFailure(ctx context.Context, eventsDB *gorm.DB, settings *models.OrganizationSettings, instance *models.WhatsappInstance) error {
+	return updateWarmupSends(ctx, eventsDB, settings, instance, false, true)
+}
+
+// releaseWarmupSend gives back the warm-up slot reserved for a send that
+// didn't go out, counting it as a failure when the send was attempted.
+func releaseWarmupSend(ctx context.Context, eventsDB *gorm.DB, settings *models.OrganizationSettings, instance *models.WhatsappInstance, failed bool) error {
+	return updateWarmupSends(ctx, eventsDB, settings, instance, true, failed)
+}
+
+func updateWarmupSends(ctx context.Context, eventsDB *gorm.DB, settings *models.OrganizationSettings, instance *models.WhatsappInstance, refund, failed bool) error {
+	schedule := warmupSchedule(settings)
+	now := time.Now()
+
+	warmupRepo := repositories.NewInstanceWarmupRepository(eventsDB)
+	_, err := warmupRepo.Update(ctx, newInstanceWarmup(instance, settings.OrganizationID, schedule, now), func(w *models.InstanceWarmup) error {
+		rolloverWarmup(w, schedule, now)
+
+		if refund && w.SentToday > 0 {
+			w.SentToday--
+		}
+		if failed {
+			w.FailedToday++
+			if attempts := w.SentToday + w.FailedToday; !w.DemotedOn(w.Day) && warmup.Unhealthy(attempts, w.FailedToday) {
+				demote(w, fmt.Sprintf("failure rate %d/%d", w.FailedToday, attempts), now)
+			}
+		}
+		w.UpdatedAt = now
+		return nil
+	})
+	return err
+}
+
+// demoteWarmup demotes the instance after a ban signal, at most once a day.
+func demoteWarmup(ctx context.Context, eventsDB *gorm.DB, settings *models.OrganizationSettings, instance *models.WhatsappInstance, reason string) error {
+	schedule := warmupSchedule(settings)
+	now := time.Now()
+
+	warmupRepo := repositories.NewInstanceWarmupRepository(eventsDB)
+	_, err := warmupRepo.Update(ctx, newInstanceWarmup(instance, settings.OrganizationID, schedule, now), func(w *models.InstanceWarmup) error {
+		rolloverWarmup(w, schedule, now)
+
+		if !w.DemotedOn(w.Day) {
+			demote(w, reason, now)
+		}
+		w.UpdatedAt = now
+		return nil
+	})
+	return err
+}
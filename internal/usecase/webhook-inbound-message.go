@@ -0,0 +1,163 @@
+package usecase
+
+import (
+	config "afrus-whatsapp-evolution_api-notification/configs"
+	"afrus-whatsapp-evolution_api-notification/internal/application/dto"
+	"afrus-whatsapp-evolution_api-notification/internal/application/repositories"
+	"afrus-whatsapp-evolution_api-notification/internal/domain/models"
+	"afrus-whatsapp-evolution_api-notification/internal/services"
+	"afrus-whatsapp-evolution_api-notification/pkg/keywords"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultOptOutKeywords            = "stop,baja,darme de baja,sair,parar,cancelar,descadastrar,unsubscribe"
+	defaultOptOutConfirmationMessage = "Tu número fue dado de baja y no recibirás más mensajes por WhatsApp."
+)
+
+type ReceiptWebhookInboundMessageUseCase struct {
+	Ctx                   context.Context
+	Configs               *config.Config
+	AfrusDB               *gorm.DB
+	EventsDB              *gorm.DB
+	whatsappSenderService *services.WhatsappSenderService
+}
+
+func NewReceiptWebhookInboundMessageUseCase(ctx context.Context, configs *config.Config, afrusDB, eventsDB *gorm.DB, whatsappSenderService *services.WhatsappSenderService) *ReceiptWebhookInboundMessageUseCase {
+	return &ReceiptWebhookInboundMessageUseCase{
+		Ctx:                   ctx,
+		Configs:               configs,
+		AfrusDB:               afrusDB,
+		EventsDB:              eventsDB,
+		whatsappSenderService: whatsappSenderService,
+	}
+}
+
+// Execute suppresses the sender of an inbound message that consists of one of
+// the organization opt-out keywords.
+func (rwi *ReceiptWebhookInboundMessageUseCase) Execute(event string) error {
+	var data dto.EvolutionWebhookEvent
+	if err := json.Unmarshal([]byte(event), &data); err != nil {
+		log.Printf("[WEBHOOK] - Failed to unmarshal event: %v", err)
+		return err
+	}
+
+	// Opt-outs suppress numbers and send replies, so they are only honored
+	// from webhooks authenticated with the shared secret
+	if rwi.Configs.EvolutionWebhookSecret == "" {
+		log.Printf("[OPT-OUT] - Ignoring inbound message: EVOLUTION_WEBHOOK_SECRET is not configured")
+		return nil
+	}
+
+	if data.Data.IsFromMe() {
+		return nil
+	}
+
+	phone := data.Data.SenderPhone()
+	text := data.Data.Text()
+	if phone == "" || text == "" {
+		return nil
+	}
+
+	whatsappInstanceRepo := repositories.NewWhatsappInstanceRepository(rwi.AfrusDB)
+	whatsappInstance, err := whatsappInstanceRepo.GetWhatsappInstanceByName(rwi.Ctx, data.Instance)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("[WEBHOOK] - Ignoring inbound message for unknown instance: %s", data.Instance)
+			return nil
+		}
+		return err
+	}
+	if !rwi.ownsInstance(data, whatsappInstance) {
+		log.Printf("[OPT-OUT] - Ignoring inbound message: instance %s doesn't match the sender %s", whatsappInstance.InstanceName, data.SenderPhone())
+		return nil
+	}
+	organizationID := int(whatsappInstance.OrganizationID)
+
+	settingsRepo := repositories.NewOrganizationSettingsRepository(rwi.EventsDB)
+	settings, err := settingsRepo.FindByOrganization(rwi.Ctx, organizationID)
+	if err != nil {
+		return err
+	}
+
+	keyword, ok := keywords.Match(text, rwi.optOutKeywords(settings))
+	if !ok {
+		return nil
+	}
+
+	// JIDs carry the international number, which the entry is stored as so
+	// domestic numbers typed in the API match it
+	optOutPhone := "+" + phone
+
+	suppressionRepo := repositories.NewSuppressionRepository(rwi.EventsDB)
+	existing, err := suppressionRepo.FindMatch(rwi.Ctx, organizationID, 0, suppressionPhone(rwi.Configs, settings, optOutPhone))
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		log.Printf("[OPT-OUT] - Phone already suppressed for organization: %d", organizationID)
+		return nil
+	}
+
+	suppressionUseCase := NewSuppressionUseCase(rwi.Ctx, rwi.Configs, rwi.EventsDB)
+	suppression, err := suppressionUseCase.Add(dto.SuppressionRequest{
+		OrganizationID: organizationID,
+		Phone:          &optOutPhone,
+		Reason:         fmt.Sprintf("opt-out keyword: %s", keyword),
+	}, models.SuppressionSourceKeyword)
+	if err != nil {
+		// A duplicate webhook suppressed it in the meantime
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			log.Printf("[OPT-OUT] - Phone already suppressed for organization: %d", organizationID)
+			return nil
+		}
+		return err
+	}
+
+	log.Printf("[OPT-OUT] - Phone suppressed for organization: %d with keyword: '%s' (suppression: %d)", organizationID, keyword, suppression.ID)
+
+	if settings.OptOutConfirmation {
+		message := settings.OptOutConfirmationMessage
+		if message == "" {
+			message = defaultOptOutConfirmationMessage
+		}
+		if _, err := rwi.whatsappSenderService.SendWhatsappTextMessage(&models.Lead{Phone: phone}, whatsappInstance, message); err != nil {
+			log.Printf("[OPT-OUT] - Error sending opt-out confirmation in instance %s: %v", whatsappInstance.InstanceName, err)
+		}
+	}
+
+	return nil
+}
+
+// ownsInstance checks that the payload comes from the number connected to the
+// instance it names, and that the instance belongs to an organization, so a
+// payload can't suppress numbers on behalf of another organization.
+func (rwi *ReceiptWebhookInboundMessageUseCase) ownsInstance(data dto.EvolutionWebhookEvent, whatsappInstance *models.WhatsappInstance) bool {
+	if whatsappInstance.OrganizationID == 0 {
+		return false
+	}
+
+	owner := dto.JIDPhone(whatsappInstance.Owner)
+	sender := data.SenderPhone()
+	if owner == "" || sender == "" {
+		// Older Evolution versions don't send the sender, nothing to compare
+		return true
+	}
+	return phoneDigits(owner) == phoneDigits(sender)
+}
+
+func (rwi *ReceiptWebhookInboundMessageUseCase) optOutKeywords(settings *models.OrganizationSettings) []string {
+	if settings.OptOutKeywords != "" {
+		return keywords.Parse(settings.OptOutKeywords)
+	}
+	if rwi.Configs.OptOutKeywords != "" {
+		return keywords.Parse(rwi.Configs.OptOutKeywords)
+	}
+	return keywords.Parse(defaultOptOutKeywords)
+}
@@ -0,0 +1,169 @@
+package usecase
+
+import (
+	config "afrus-whatsapp-evolution_api-notification/configs"
+	"afrus-whatsapp-evolution_api-notification/internal/application/dto"
+	"afrus-whatsapp-evolution_api-notification/internal/application/repositories"
+	"afrus-whatsapp-evolution_api-notification/internal/domain/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// parkedStatusTTL bounds how long a status waits for its sent event. Statuses
+// of messages not sent by this service (e.g. typed on the phone) never get
+// one.
+const parkedStatusTTL = 24 * time.Hour
+
+// evolutionStatusEvents maps Evolution message statuses to the events table
+// that records them.
+var evolutionStatusEvents = map[dto.EvolutionStatus]string{
+	"PENDING":      "queued",
+	"SERVER_ACK":   "accepted",
+	"DELIVERY_ACK": "delivered",
+	"READ":         "read",
+	"PLAYED":       "read",
+	"ERROR":        "failed",
+}
+
+type ReceiptWebhookStatusEventUseCase struct {
+	Ctx      context.Context
+	Configs  *config.Config
+	EventsDB *gorm.DB
+}
+
+func NewReceiptWebhookStatusEventUseCase(ctx context.Context, configs *config.Config, eventsDB *gorm.DB) *ReceiptWebhookStatusEventUseCase {
+	return &ReceiptWebhookStatusEventUseCase{
+		Ctx:      ctx,
+		Configs:  configs,
+		EventsDB: eventsDB,
+	}
+}
+
+func (rws *ReceiptWebhookStatusEventUseCase) Execute(event string) error {
+	var data dto.EvolutionWebhookEvent
+	if err := json.Unmarshal([]byte(event), &data); err != nil {
+		log.Printf("[WEBHOOK] - Failed to unmarshal event: %v", err)
+		return err
+	}
+
+	kind, ok := rws.eventKind(data)
+	if !ok {
+		log.Printf("[WEBHOOK] - Ignoring event '%s' with status '%s'", data.Event, data.Data.Status)
+		return nil
+	}
+
+	messageID := data.Data.MessageID()
+	if messageID == "" {
+		log.Printf("[WEBHOOK] - Ignoring event '%s' without message id", data.Event)
+		return nil
+	}
+
+	var eventMap models.JSONB
+	if err := json.Unmarshal([]byte(event), &eventMap); err != nil {
+		return fmt.Errorf("error unmarshalling webhook event: %v", err)
+	}
+
+	eventRepo := repositories.NewWhatsappEventRepository(rws.EventsDB)
+
+	sent, err := eventRepo.FindByMessageID(rws.Ctx, "sent", messageID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return rws.park(kind, messageID, eventMap)
+		}
+		return err
+	}
+
+	return saveStatusEvent(rws.Ctx, eventRepo, kind, sent, eventMap)
+}
+
+// park keeps a status that arrived before its sent event was stored, which
+// is common for send.message and early acks, so replayStatusEvents can
+// record it once the sent event is stored.
+func (rws *ReceiptWebhookStatusEventUseCase) park(kind, messageID string, eventMap models.JSONB) error {
+	now := time.Now()
+	pendingRepo := repositories.NewPendingStatusEventRepository(rws.EventsDB)
+	if err := pendingRepo.Park(rws.Ctx, &models.PendingStatusEvent{
+		MessageID:  messageID,
+		Kind:       kind,
+		Event:      eventMap,
+		ReceivedAt: now,
+		ExpiresAt:  now.Add(parkedStatusTTL),
+	}); err != nil {
+		return fmt.Errorf("error parking status event: %v", err)
+	}
+
+	log.Printf("[WEBHOOK] - No sent event found for message: %s - parked '%s' status", messageID, kind)
+
+	// The sent event may have been stored while parking, in which case no
+	// replay would pick the status up
+	return replayStatusEvents(rws.Ctx, rws.EventsDB, messageID)
+}
+
+// replayStatusEvents records the statuses parked for a message once its sent
+// event exists. It does nothing while the sent event is missing.
+func replayStatusEvents(ctx context.Context, eventsDB *gorm.DB, messageID string) error {
+	if messageID == "" {
+		return nil
+	}
+
+	sent, err := repositories.NewWhatsappEventRepository(eventsDB).FindByMessageID(ctx, "sent", messageID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	pendingRepo := repositories.NewPendingStatusEventRepository(eventsDB)
+	return pendingRepo.Take(ctx, messageID, func(tx *gorm.DB, pending *models.PendingStatusEvent) error {
+		return saveStatusEvent(ctx, repositories.NewWhatsappEventRepository(tx), pending.Kind, sent, pending.Event)
+	})
+}
+
+// saveStatusEvent records the status of the sent message, once per kind.
+func saveStatusEvent(ctx context.Context, eventRepo *repositories.WhatsappEventRepository, kind string, sent *models.WhatsappEvent, eventMap models.JSONB) error {
+	exists, err := eventRepo.ExistsByMessageID(ctx, kind, sent.MessageID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+
+	statusEvent := &models.WhatsappEvent{
+		LeadID:         sent.LeadID,
+		OrganizationID: sent.OrganizationID,
+		PhoneNumber:    sent.PhoneNumber,
+		ExternalID:     sent.ExternalID,
+		ExternalTable:  sent.ExternalTable,
+		MessageID:      sent.MessageID,
+		EventType:      sent.EventType,
+		DateEvent:      time.Now().Format(time.RFC3339),
+		Event:          eventMap,
+	}
+
+	if err := eventRepo.Save(ctx, kind, statusEvent); err != nil {
+		return fmt.Errorf("[EVENT] - error saving event: %v", err)
+	}
+
+	log.Printf("[EVENT] - Event of type: '%s' for message: '%s' saved successfully", kind, sent.MessageID)
+
+	return nil
+}
+
+func (rws *ReceiptWebhookStatusEventUseCase) eventKind(data dto.EvolutionWebhookEvent) (string, bool) {
+	switch data.EventName() {
+	case dto.EvolutionEventSendMessage:
+		return "accepted", true
+	case dto.EvolutionEventMessagesUpdate:
+		kind, ok := evolutionStatusEvents[data.Data.Status]
+		return kind, ok
+	}
+	return "", false
+}
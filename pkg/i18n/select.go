@@ -0,0 +1,81 @@
+package i18n
+
+import (
+	"sort"
+	"strings"
+)
+
+// Normalize lowercases a language tag and uses "-" as separator so "pt_BR"
+// and "pt-br" compare equal.
+func Normalize(languageCode string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(languageCode), "_", "-"))
+}
+
+// Base returns the primary language subtag ("pt" for "pt-BR").
+func Base(languageCode string) string {
+	base, _, _ := strings.Cut(Normalize(languageCode), "-")
+	return base
+}
+
+// Select picks the variant for languageCode walking the fallback chain: the
+// exact tag, its base language, the first regional variant of that base in
+// alphabetical order, and then each fallback language in order. It reports
+// false when nothing matched so the caller can use its default content.
+func Select(variants map[string]string, languageCode string, fallbacks []string) (string, bool) {
+	normalized := make(map[string]string, len(variants))
+	for code, content := range variants {
+		if content == "" {
+			continue
+		}
+		normalized[Normalize(code)] = content
+	}
+
+	for _, code := range append([]string{languageCode}, fallbacks...) {
+		if content, ok := lookup(normalized, code); ok {
+			return content, true
+		}
+	}
+
+	return "", false
+}
+
+func lookup(variants map[string]string, languageCode string) (string, bool) {
+	code := Normalize(languageCode)
+	if code == "" {
+		return "", false
+	}
+
+	if content, ok := variants[code]; ok {
+		return content, true
+	}
+
+	base := Base(code)
+	if content, ok := variants[base]; ok {
+		return content, true
+	}
+
+	// Map order is random, so the regional variants are sorted to always
+	// pick the same one
+	var regional []string
+	for variant := range variants {
+		if Base(variant) == base {
+			regional = append(regional, variant)
+		}
+	}
+	if len(regional) == 0 {
+		return "", false
+	}
+	sort.Strings(regional)
+	return variants[regional[0]], true
+}
+
+// ParseFallbacks splits a comma separated list of language tags.
+func ParseFallbacks(value string) []string {
+	var fallbacks []string
+	for _, code := range strings.Split(value, ",") {
+		if code = Normalize(code); code != "" {
+			fallbacks = append(fallbacks, code)
+		}
+	}
+	return fallbacks
+}
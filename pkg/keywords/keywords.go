@@ -0,0 +1,52 @@
+package keywords
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Normalize lowercases text, strips accents and surrounding punctuation and
+// collapses whitespace so "¡BAJA!" and "baja" compare equal.
+func Normalize(text string) string {
+	stripAccents := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	stripped, _, err := transform.String(stripAccents, text)
+	if err != nil {
+		stripped = text
+	}
+
+	fields := strings.FieldsFunc(strings.ToLower(stripped), func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r)
+	})
+	return strings.Join(fields, " ")
+}
+
+// Parse splits a comma separated keyword list, normalizing every entry.
+func Parse(value string) []string {
+	var parsed []string
+	for _, keyword := range strings.Split(value, ",") {
+		if keyword = Normalize(keyword); keyword != "" {
+			parsed = append(parsed, keyword)
+		}
+	}
+	return parsed
+}
+
+// Match reports which keyword the whole message equals, if any. Keywords
+// embedded in longer sentences do not match to avoid accidental opt-outs.
+func Match(text string, keywords []string) (string, bool) {
+	normalized := Normalize(text)
+	if normalized == "" {
+		return "", false
+	}
+
+	for _, keyword := range keywords {
+		if normalized == Normalize(keyword) {
+			return keyword, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,31 @@
+package pacing
+
+import (
+	"time"
+
+	"golang.org/x/exp/rand"
+)
+
+// Pacer spaces the sends of an instance with a random gap between MinGap and
+// MaxGap, so messages leave at a human-like rhythm without blocking workers.
+type Pacer struct {
+	MinGap time.Duration
+	MaxGap time.Duration
+}
+
+// Claim returns the slot of a message arriving at now for an instance whose
+// next free slot is nextFree, and the instance's next free slot after it.
+func (p Pacer) Claim(nextFree, now time.Time) (slot, next time.Time) {
+	slot = now
+	if nextFree.After(now) {
+		slot = nextFree
+	}
+	return slot, slot.Add(p.gap())
+}
+
+func (p Pacer) gap() time.Duration {
+	if p.MaxGap <= p.MinGap {
+		return p.MinGap
+	}
+	return p.MinGap + time.Duration(rand.Int63n(int64(p.MaxGap-p.MinGap)))
+}
@@ -0,0 +1,112 @@
+package phone
+
+import "strings"
+
+// country describes how national numbers are written in a country.
+type country struct {
+	CallingCode string
+	// TrunkPrefix is dialed before national numbers inside the country and
+	// never belongs to the international form.
+	TrunkPrefix string
+	MinLength   int
+	MaxLength   int
+	// Normalize rewrites the national significant number into the form
+	// WhatsApp expects. The lengths are checked on its result.
+	Normalize func(national string) (string, error)
+}
+
+var countries = map[string]country{
+	"AR": {CallingCode: "54", TrunkPrefix: "0", MinLength: 11, MaxLength: 11, Normalize: normalizeArgentina},
+	"BO": {CallingCode: "591", MinLength: 8, MaxLength: 8},
+	"BR": {CallingCode: "55", TrunkPrefix: "0", MinLength: 10, MaxLength: 11, Normalize: normalizeBrazil},
+	"CL": {CallingCode: "56", MinLength: 9, MaxLength: 9},
+	"CO": {CallingCode: "57", MinLength: 10, MaxLength: 10},
+	"CR": {CallingCode: "506", MinLength: 8, MaxLength: 8},
+	"DE": {CallingCode: "49", TrunkPrefix: "0", MinLength: 6, MaxLength: 13},
+	"EC": {CallingCode: "593", TrunkPrefix: "0", MinLength: 8, MaxLength: 9},
+	"ES": {CallingCode: "34", MinLength: 9, MaxLength: 9},
+	"FR": {CallingCode: "33", TrunkPrefix: "0", MinLength: 9, MaxLength: 9},
+	"GB": {CallingCode: "44", TrunkPrefix: "0", MinLength: 9, MaxLength: 10},
+	"GT": {CallingCode: "502", MinLength: 8, MaxLength: 8},
+	"HN": {CallingCode: "504", MinLength: 8, MaxLength: 8},
+	"IT": {CallingCode: "39", MinLength: 6, MaxLength: 11},
+	"MX": {CallingCode: "52", MinLength: 10, MaxLength: 10, Normalize: normalizeMexico},
+	"NI": {CallingCode: "505", MinLength: 8, MaxLength: 8},
+	"PA": {CallingCode: "507", MinLength: 7, MaxLength: 8},
+	"PE": {CallingCode: "51", TrunkPrefix: "0", MinLength: 8, MaxLength: 9},
+	"PT": {CallingCode: "351", MinLength: 9, MaxLength: 9},
+	"PY": {CallingCode: "595", TrunkPrefix: "0", MinLength: 9, MaxLength: 9},
+	"SV": {CallingCode: "503", MinLength: 8, MaxLength: 8},
+	"US": {CallingCode: "1", TrunkPrefix: "1", MinLength: 10, MaxLength: 10},
+	"UY": {CallingCode: "598", TrunkPrefix: "0", MinLength: 8, MaxLength: 8},
+	"VE": {CallingCode: "58", TrunkPrefix: "0", MinLength: 10, MaxLength: 10},
+}
+
+// countryByCallingCode matches the calling code at the start of digits,
+// trying the longest codes first.
+func countryByCallingCode(digits string) (country, bool) {
+	for length := 3; length >= 1; length-- {
+		if len(digits) <= length {
+			continue
+		}
+		for _, c := range countries {
+			if c.CallingCode == digits[:length] {
+				return c, true
+			}
+		}
+	}
+	return country{}, false
+}
+
+// normalizeBrazil drops the carrier selection code dialed after the trunk
+// prefix (0 XX) and restores the ninth digit of mobile numbers still stored in
+// the old 8-digit format.
+func normalizeBrazil(national string) (string, error) {
+	if len(national) == 12 || len(national) == 13 {
+		national = national[2:]
+	}
+	if len(national) < 10 {
+		return "", invalid("Brazilian numbers must have an area code and 8 or 9 digits")
+	}
+	if national[0] == '0' || national[1] == '0' {
+		return "", invalid("invalid Brazilian area code")
+	}
+
+	areaCode, subscriber := national[:2], national[2:]
+	switch {
+	case len(subscriber) == 9 && subscriber[0] != '9':
+		return "", invalid("9-digit Brazilian numbers must be mobiles starting with 9")
+	case len(subscriber) == 8 && subscriber[0] >= '6':
+		return areaCode + "9" + subscriber, nil
+	}
+	return national, nil
+}
+
+// normalizeArgentina converts the domestic mobile form (area code + 15 +
+// subscriber) and adds the 9 mobile prefix WhatsApp requires after +54.
+func normalizeArgentina(national string) (string, error) {
+	national = strings.TrimPrefix(national, "9")
+	if len(national) == 12 {
+		for areaLength := 2; areaLength <= 4; areaLength++ {
+			if national[areaLength:areaLength+2] == "15" {
+				national = national[:areaLength] + national[areaLength+2:]
+				break
+			}
+		}
+	}
+	if len(national) != 10 {
+		return "", invalid("Argentine numbers must have 10 digits after the area code prefix")
+	}
+	return "9" + national, nil
+}
+
+// normalizeMexico drops the 1 that used to precede mobile numbers after +52.
+func normalizeMexico(national string) (string, error) {
+	if len(national) == 11 && national[0] == '1' {
+		return national[1:], nil
+	}
+	if len(national) != 10 {
+		return "", invalid("Mexican numbers must have 10 digits")
+	}
+	return national, nil
+}
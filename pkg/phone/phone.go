@@ -0,0 +1,122 @@
+package phone
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrInvalidNumber = errors.New("invalid phone number")
+
+func invalid(reason string) error {
+	return fmt.Errorf("%w: %s", ErrInvalidNumber, reason)
+}
+
+// Number is a phone number split into its calling code and national
+// significant number.
+type Number struct {
+	CallingCode string
+	National    string
+}
+
+// E164 formats the number as +<calling code><national number>.
+func (n Number) E164() string {
+	return "+" + n.Digits()
+}
+
+// Digits formats the number as Evolution API expects it, without the plus.
+func (n Number) Digits() string {
+	return n.CallingCode + n.National
+}
+
+// Parse normalizes raw into an international number. Numbers written with a
+// + or 00 prefix are read as international; anything else is tried as a
+// number of defaultCountry (an ISO 3166 alpha-2 code), with or without its
+// calling code, before falling back to the international reading.
+func Parse(raw, defaultCountry string) (Number, error) {
+	digits, international, err := clean(raw)
+	if err != nil {
+		return Number{}, err
+	}
+
+	if !international {
+		if c, ok := countries[strings.ToUpper(defaultCountry)]; ok {
+			if national, ok := strings.CutPrefix(digits, c.CallingCode); ok {
+				if number, err := parseNational(c, national, false); err == nil {
+					return number, nil
+				}
+			}
+			if number, err := parseNational(c, digits, true); err == nil {
+				return number, nil
+			}
+		}
+	}
+
+	return parseInternational(digits)
+}
+
+// clean strips the formatting characters people type around numbers and
+// reports whether the number was written in international form.
+func clean(raw string) (string, bool, error) {
+	raw = strings.TrimSpace(raw)
+	international := strings.HasPrefix(raw, "+")
+
+	var digits strings.Builder
+	for i, r := range raw {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == '+' && i == 0:
+		case strings.ContainsRune(" -.()/", r):
+		default:
+			return "", false, invalid(fmt.Sprintf("unexpected character %q", r))
+		}
+	}
+
+	result := digits.String()
+	if !international && strings.HasPrefix(result, "00") {
+		result, international = result[2:], true
+	}
+	if result == "" {
+		return "", false, invalid("no digits")
+	}
+	return result, international, nil
+}
+
+func parseInternational(digits string) (Number, error) {
+	c, ok := countryByCallingCode(digits)
+	if !ok {
+		// Unknown countries only get the generic E.164 length check
+		if len(digits) < 8 || len(digits) > 15 {
+			return Number{}, invalid(fmt.Sprintf("%d digits is not a valid international number", len(digits)))
+		}
+		return Number{National: digits}, nil
+	}
+	return parseNational(c, digits[len(c.CallingCode):], false)
+}
+
+func parseNational(c country, national string, domestic bool) (Number, error) {
+	if domestic && c.TrunkPrefix != "" {
+		national = strings.TrimPrefix(national, c.TrunkPrefix)
+	}
+	// Leading zeros are never part of these national numbers
+	if c.TrunkPrefix == "0" {
+		national = strings.TrimLeft(national, "0")
+	}
+	if national == "" {
+		return Number{}, invalid("missing national number")
+	}
+
+	if c.Normalize != nil {
+		normalized, err := c.Normalize(national)
+		if err != nil {
+			return Number{}, err
+		}
+		national = normalized
+	}
+
+	if len(national) < c.MinLength || len(national) > c.MaxLength {
+		return Number{}, invalid(fmt.Sprintf("%d digits is not a valid national number for +%s", len(national), c.CallingCode))
+	}
+	return Number{CallingCode: c.CallingCode, National: national}, nil
+}
@@ -0,0 +1,86 @@
+package phone
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name           string
+		raw            string
+		defaultCountry string
+		want           string
+	}{
+		// Brazil
+		{name: "BR international mobile", raw: "+55 11 98765-4321", defaultCountry: "BR", want: "5511987654321"},
+		{name: "BR adds ninth digit to old mobile", raw: "+55 11 8765-4321", defaultCountry: "BR", want: "5511987654321"},
+		{name: "BR keeps landline", raw: "+55 11 3456-7890", defaultCountry: "BR", want: "551134567890"},
+		{name: "BR domestic mobile", raw: "(11) 98765-4321", defaultCountry: "BR", want: "5511987654321"},
+		{name: "BR drops trunk and carrier code", raw: "0 21 11 98765-4321", defaultCountry: "BR", want: "5511987654321"},
+		{name: "BR calling code without plus", raw: "55 11 98765-4321", defaultCountry: "BR", want: "5511987654321"},
+
+		// Argentina
+		{name: "AR international with 9", raw: "+54 9 11 1234-5678", defaultCountry: "AR", want: "5491112345678"},
+		{name: "AR international adds 9", raw: "+54 11 1234-5678", defaultCountry: "AR", want: "5491112345678"},
+		{name: "AR domestic with 15", raw: "011 15 1234-5678", defaultCountry: "AR", want: "5491112345678"},
+
+		// Mexico
+		{name: "MX drops leading 1", raw: "+52 1 55 1234 5678", defaultCountry: "MX", want: "525512345678"},
+		{name: "MX international", raw: "+52 55 1234 5678", defaultCountry: "MX", want: "525512345678"},
+		{name: "MX domestic", raw: "55 1234 5678", defaultCountry: "MX", want: "525512345678"},
+
+		// Trunk prefixes
+		{name: "GB drops trunk 0", raw: "07911 123456", defaultCountry: "GB", want: "447911123456"},
+		{name: "FR drops trunk 0", raw: "06 12 34 56 78", defaultCountry: "FR", want: "33612345678"},
+		{name: "US drops trunk 1", raw: "1 (415) 555-2671", defaultCountry: "US", want: "14155552671"},
+		{name: "US domestic", raw: "415.555.2671", defaultCountry: "US", want: "14155552671"},
+
+		// International forms win over the default country
+		{name: "00 prefix", raw: "0044 7911 123456", defaultCountry: "BR", want: "447911123456"},
+		{name: "plus prefix", raw: "+44 7911 123456", defaultCountry: "BR", want: "447911123456"},
+		{name: "unknown calling code", raw: "+999 1234 5678", defaultCountry: "BR", want: "99912345678"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			number, err := Parse(tt.raw, tt.defaultCountry)
+			if err != nil {
+				t.Fatalf("Parse(%q, %q) returned error: %v", tt.raw, tt.defaultCountry, err)
+			}
+			if got := number.Digits(); got != tt.want {
+				t.Errorf("Parse(%q, %q) = %s, want %s", tt.raw, tt.defaultCountry, got, tt.want)
+			}
+			if got := number.E164(); got != "+"+tt.want {
+				t.Errorf("E164() = %s, want +%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		name           string
+		raw            string
+		defaultCountry string
+	}{
+		{name: "empty", raw: "", defaultCountry: "BR"},
+		{name: "letters", raw: "11 9876-abcd", defaultCountry: "BR"},
+		{name: "plus in the middle", raw: "55+11987654321", defaultCountry: "BR"},
+		{name: "BR 9-digit landline", raw: "+55 11 88765-4321", defaultCountry: "BR"},
+		{name: "BR area code ending in 0", raw: "+55 10 98765-4321", defaultCountry: "BR"},
+		{name: "AR too short", raw: "+54 9 11 1234-567", defaultCountry: "AR"},
+		{name: "MX too long", raw: "+52 55 1234 56789", defaultCountry: "MX"},
+		{name: "US too short", raw: "+1 415 555", defaultCountry: "US"},
+		{name: "unknown calling code too short", raw: "+999 123", defaultCountry: "BR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			number, err := Parse(tt.raw, tt.defaultCountry)
+			if !errors.Is(err, ErrInvalidNumber) {
+				t.Errorf("Parse(%q, %q) = %s, %v, want ErrInvalidNumber", tt.raw, tt.defaultCountry, number.Digits(), err)
+			}
+		})
+	}
+}
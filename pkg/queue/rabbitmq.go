@@ -3,17 +3,52 @@ package queue
 import (
 	config "afrus-whatsapp-evolution_api-notification/configs"
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"sync"
 	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
+const (
+	reconnectInitialDelay = 1 * time.Second
+	reconnectMaxDelay     = 30 * time.Second
+	defaultWorkers        = 300
+	confirmTimeout        = 10 * time.Second
+)
+
+var (
+	ErrNotConnected   = errors.New("[RABBITMQ] - not connected")
+	ErrPublishNacked  = errors.New("[RABBITMQ] - message nacked by broker")
+	ErrConfirmTimeout = errors.New("[RABBITMQ] - timed out waiting for publisher confirm")
+)
+
 type QueueConfig struct {
-	Name       string
-	BufferSize int
-	Consumer   string
+	Name          string
+	BufferSize    int
+	Consumer      string
+	PrefetchCount int
+	Workers       int
+}
+
+// Concurrency returns how many deliveries of the queue may be processed in
+// parallel.
+func (c QueueConfig) Concurrency() int {
+	if c.Workers <= 0 {
+		return defaultWorkers
+	}
+	return c.Workers
+}
+
+// Prefetch returns the broker-side limit of unacked deliveries, which defaults
+// to the worker concurrency so no delivery waits in memory for a free worker.
+func (c QueueConfig) Prefetch() int {
+	if c.PrefetchCount <= 0 {
+		return c.Concurrency()
+	}
+	return c.PrefetchCount
 }
 
 type RabbitMQ struct {
@@ -21,23 +56,39 @@ type RabbitMQ struct {
 	Connection *amqp.Connection
 	Configs    *config.Config
 	Queues     map[string]chan *amqp.Delivery
+
+	// publishChannel runs in confirm mode and is kept apart from the consumer
+	// channel so confirms are not interleaved with deliveries.
+	publishChannel *amqp.Channel
+	queueConfigs   map[string]QueueConfig
+	topology       *Topology
+	mu             sync.RWMutex
+	consumers      sync.WaitGroup
+	done           chan struct{}
+	closed         bool
 }
 
 func NewRabbitMQ(configs *config.Config) *RabbitMQ {
 	return &RabbitMQ{
-		Channel:    nil,
-		Connection: nil,
-		Configs:    configs,
-		Queues:     make(map[string]chan *amqp.Delivery),
+		Channel:      nil,
+		Connection:   nil,
+		Configs:      configs,
+		Queues:       make(map[string]chan *amqp.Delivery),
+		queueConfigs: make(map[string]QueueConfig),
+		done:         make(chan struct{}),
 	}
 }
 
 func (r *RabbitMQ) AddQueue(config QueueConfig) (chan *amqp.Delivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	msgs := make(chan *amqp.Delivery, config.BufferSize)
 	r.Queues[config.Name] = msgs
+	r.queueConfigs[config.Name] = config
 
 	if r.Channel != nil {
-		if err := r.startConsuming(config, msgs); err != nil {
+		if err := r.startConsuming(r.Channel, config, msgs); err != nil {
 			return nil, err
 		}
 	}
@@ -46,19 +97,11 @@ func (r *RabbitMQ) AddQueue(config QueueConfig) (chan *amqp.Delivery, error) {
 }
 
 func (r *RabbitMQ) Connect() error {
-	connectionString := fmt.Sprintf("%s://%s:%s@%s:%s",
-		getRabbitMQProtocol(r.Configs.Environment),
-		r.Configs.RabbitMQUser,
-		r.Configs.RabbitMQPassword,
-		r.Configs.RabbitMQUrl,
-		r.Configs.RabbitMQPort,
-	)
-
 	var conn *amqp.Connection
 	var err error
 
 	for retries := 0; retries < 5; retries++ {
-		conn, err = amqp.Dial(connectionString)
+		conn, err = amqp.Dial(r.connectionString())
 		if err == nil {
 			break
 		}
@@ -69,34 +112,152 @@ func (r *RabbitMQ) Connect() error {
 		return fmt.Errorf("failed to connect to RabbitMQ after retries: %w", err)
 	}
 
-	r.Connection = conn
+	if err := r.setup(conn); err != nil {
+		conn.Close()
+		return err
+	}
+
+	log.Printf("[RABBITMQ] - Connected to RabbitMQ")
+
+	return nil
+}
+
+func (r *RabbitMQ) connectionString() string {
+	return fmt.Sprintf("%s://%s:%s@%s:%s",
+		getRabbitMQProtocol(r.Configs.Environment),
+		r.Configs.RabbitMQUser,
+		r.Configs.RabbitMQPassword,
+		r.Configs.RabbitMQUrl,
+		r.Configs.RabbitMQPort,
+	)
+}
+
+// setup declares the topology, opens a channel on conn, re-subscribes every
+// registered queue onto its existing delivery channel and starts watching for
+// connection loss.
+func (r *RabbitMQ) setup(conn *amqp.Connection) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.declareTopology(conn); err != nil {
+		return err
+	}
 
 	ch, err := conn.Channel()
 	if err != nil {
 		return fmt.Errorf("failed to open RabbitMQ channel: %w", err)
 	}
 
-	r.Channel = ch
+	publishCh, err := conn.Channel()
+	if err != nil {
+		ch.Close()
+		return fmt.Errorf("failed to open RabbitMQ publish channel: %w", err)
+	}
 
-	log.Printf("[RABBITMQ] - Connected to RabbitMQ")
+	if err := publishCh.Confirm(false); err != nil {
+		ch.Close()
+		publishCh.Close()
+		return fmt.Errorf("failed to put RabbitMQ publish channel in confirm mode: %w", err)
+	}
 
 	// Start consuming for all registered queues
-	for name, msgs := range r.Queues {
-		config := QueueConfig{
-			Name:       name,
-			BufferSize: cap(msgs),
-			Consumer:   fmt.Sprintf("%s-consumer", name),
-		}
-		if err := r.startConsuming(config, msgs); err != nil {
+	for name, config := range r.queueConfigs {
+		if err := r.startConsuming(ch, config, r.Queues[name]); err != nil {
+			ch.Close()
+			publishCh.Close()
 			return err
 		}
 	}
 
+	r.Connection = conn
+	r.Channel = ch
+	r.publishChannel = publishCh
+
+	go r.watch(conn, ch, publishCh)
+
 	return nil
 }
 
-func (r *RabbitMQ) startConsuming(config QueueConfig, msgs chan<- *amqp.Delivery) error {
-	deliveries, err := r.Channel.Consume(
+func (r *RabbitMQ) watch(conn *amqp.Connection, ch, publishCh *amqp.Channel) {
+	connClosed := conn.NotifyClose(make(chan *amqp.Error, 1))
+	chanClosed := ch.NotifyClose(make(chan *amqp.Error, 1))
+	publishChanClosed := publishCh.NotifyClose(make(chan *amqp.Error, 1))
+
+	var reason *amqp.Error
+	select {
+	case <-r.done:
+		return
+	case reason = <-connClosed:
+	case reason = <-chanClosed:
+	case reason = <-publishChanClosed:
+	}
+
+	if r.isClosed() {
+		return
+	}
+
+	log.Printf("[RABBITMQ] - Connection lost: %v", reason)
+
+	// A closed channel on a live connection is torn down as well so everything
+	// is re-established together.
+	if !conn.IsClosed() {
+		conn.Close()
+	}
+
+	r.reconnect()
+}
+
+func (r *RabbitMQ) reconnect() {
+	delay := reconnectInitialDelay
+
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-r.done:
+			return
+		case <-time.After(delay):
+		}
+
+		conn, err := amqp.Dial(r.connectionString())
+		if err == nil {
+			if err = r.setup(conn); err == nil {
+				log.Printf("[RABBITMQ] - Reconnected to RabbitMQ after %d attempt(s)", attempt)
+				return
+			}
+			conn.Close()
+		}
+
+		log.Printf("[RABBITMQ] - Reconnect attempt %d failed: %v - retrying in %s", attempt, err, delay)
+
+		delay *= 2
+		if delay > reconnectMaxDelay {
+			delay = reconnectMaxDelay
+		}
+	}
+}
+
+func (r *RabbitMQ) isClosed() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.closed
+}
+
+func (r *RabbitMQ) confirmChannel() (*amqp.Channel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.publishChannel == nil || r.publishChannel.IsClosed() {
+		return nil, ErrNotConnected
+	}
+	return r.publishChannel, nil
+}
+
+func (r *RabbitMQ) startConsuming(ch *amqp.Channel, config QueueConfig, msgs chan<- *amqp.Delivery) error {
+	// Non-global QoS only applies to consumers started after it on the channel
+	if err := ch.Qos(config.Prefetch(), 0, false); err != nil {
+		return fmt.Errorf("failed to set QoS for %s: %w", config.Name, err)
+	}
+
+	deliveries, err := ch.Consume(
 		config.Name,
 		config.Consumer,
 		false, // Auto-Ack
@@ -109,9 +270,15 @@ func (r *RabbitMQ) startConsuming(config QueueConfig, msgs chan<- *amqp.Delivery
 		return err
 	}
 
+	r.consumers.Add(1)
 	go func() {
+		defer r.consumers.Done()
 		for msg := range deliveries {
-			msgs <- &msg
+			select {
+			case msgs <- &msg:
+			case <-r.done:
+				return
+			}
 		}
 	}()
 
@@ -126,56 +293,94 @@ func getRabbitMQProtocol(environment string) string {
 }
 
 func (r *RabbitMQ) Publish(ctx context.Context, exchange, routingKey string, body []byte) error {
-	err := r.Channel.PublishWithContext(
-		ctx,
-		exchange,
-		routingKey,
-		false,
-		false,
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        body,
-		},
-	)
+	err := r.publish(ctx, exchange, routingKey, amqp.Publishing{
+		ContentType: "application/json",
+		Body:        body,
+	})
 	if err != nil {
 		return fmt.Errorf("[RABBITMQ] - failed to publish message: %w", err)
 	}
 	return nil
 }
 
-func (r *RabbitMQ) Schedule(exchange, routingKey string, body []byte, delay int) error {
-	err := r.Channel.Publish(
-		exchange,
-		routingKey,
-		false,
-		false,
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        body,
-			Headers: amqp.Table{
-				"x-delay": delay,
-			},
+// Schedule publishes body through the delayed-message exchange so it is
+// delivered after delay.
+func (r *RabbitMQ) Schedule(ctx context.Context, exchange, routingKey string, body []byte, delay time.Duration) error {
+	err := r.publish(ctx, exchange, routingKey, amqp.Publishing{
+		ContentType: "application/json",
+		Body:        body,
+		Headers: amqp.Table{
+			"x-delay": delay.Milliseconds(),
 		},
-	)
+	})
 	if err != nil {
 		return fmt.Errorf("[RABBITMQ] - failed to schedule message: %w", err)
 	}
 	return nil
 }
 
+// publish sends msg on the confirm channel and blocks until the broker acks
+// it, nacks it or ctx (bounded by confirmTimeout) expires.
+func (r *RabbitMQ) publish(ctx context.Context, exchange, routingKey string, msg amqp.Publishing) error {
+	ch, err := r.confirmChannel()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, confirmTimeout)
+		defer cancel()
+	}
+
+	confirmation, err := ch.PublishWithDeferredConfirmWithContext(ctx, exchange, routingKey, false, false, msg)
+	if err != nil {
+		return err
+	}
+
+	acked, err := confirmation.WaitContext(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrConfirmTimeout, err)
+	}
+	if !acked {
+		return ErrPublishNacked
+	}
+	return nil
+}
+
 func (r *RabbitMQ) Close() error {
-	if r.Channel != nil {
-		if err := r.Channel.Close(); err != nil {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	close(r.done)
+
+	channel, publishChannel, connection := r.Channel, r.publishChannel, r.Connection
+	r.Channel = nil
+	r.publishChannel = nil
+	r.Connection = nil
+	r.mu.Unlock()
+
+	if publishChannel != nil && !publishChannel.IsClosed() {
+		if err := publishChannel.Close(); err != nil {
+			return fmt.Errorf("failed to close RabbitMQ publish channel: %w", err)
+		}
+	}
+	if channel != nil && !channel.IsClosed() {
+		if err := channel.Close(); err != nil {
 			return fmt.Errorf("failed to close RabbitMQ channel: %w", err)
 		}
-		r.Channel = nil
 	}
-	if r.Connection != nil {
-		if err := r.Connection.Close(); err != nil {
+	if connection != nil && !connection.IsClosed() {
+		if err := connection.Close(); err != nil {
 			return fmt.Errorf("failed to close RabbitMQ connection: %w", err)
 		}
-		r.Connection = nil
 	}
+
+	// Consumers must stop forwarding before their delivery channels are closed
+	r.consumers.Wait()
 	for _, msgs := range r.Queues {
 		close(msgs)
 	}
@@ -0,0 +1,162 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	RetryCountHeader         = "x-retry-count"
+	LastErrorHeader          = "x-last-error"
+	OriginalExchangeHeader   = "x-original-exchange"
+	OriginalRoutingKeyHeader = "x-original-routing-key"
+
+	defaultRetryMaxAttempts = 5
+	defaultRetryBaseDelay   = 30 * time.Second
+	defaultRetryMaxDelay    = 30 * time.Minute
+)
+
+// permanentError marks a failure that retrying can't fix.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks err as a failure that retrying can't fix (e.g. a malformed
+// message or a missing record), so Retry dead-letters the message right away.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err, or any error it wraps, was marked with
+// Permanent.
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
+
+// RetryPolicy describes how a failed delivery is re-published: with an
+// exponentially growing delay through the delayed exchange, and to the
+// dead-letter exchange once MaxAttempts retries have been spent or the
+// failure is permanent.
+type RetryPolicy struct {
+	MaxAttempts          int
+	BaseDelay            time.Duration
+	MaxDelay             time.Duration
+	Exchange             string
+	RoutingKey           string
+	DeadLetterExchange   string
+	DeadLetterRoutingKey string
+}
+
+func NewRetryPolicy(maxAttempts int, baseDelay time.Duration, exchange, routingKey, deadLetterExchange, deadLetterRoutingKey string) RetryPolicy {
+	if maxAttempts <= 0 {
+		maxAttempts = defaultRetryMaxAttempts
+	}
+	if baseDelay <= 0 {
+		baseDelay = defaultRetryBaseDelay
+	}
+
+	return RetryPolicy{
+		MaxAttempts:          maxAttempts,
+		BaseDelay:            baseDelay,
+		MaxDelay:             defaultRetryMaxDelay,
+		Exchange:             exchange,
+		RoutingKey:           routingKey,
+		DeadLetterExchange:   deadLetterExchange,
+		DeadLetterRoutingKey: deadLetterRoutingKey,
+	}
+}
+
+// Delay returns the wait before the given retry attempt (1-based).
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= p.MaxDelay {
+			return p.MaxDelay
+		}
+	}
+	return delay
+}
+
+// Exhausted reports whether a message with headers that failed with cause
+// goes to the dead-letter exchange instead of being retried.
+func (p RetryPolicy) Exhausted(headers amqp.Table, cause error) bool {
+	return IsPermanent(cause) || RetryCount(headers)+1 > p.MaxAttempts
+}
+
+// Retry re-publishes msg according to policy after it failed with cause. The
+// caller must still settle the original delivery: ack it when Retry succeeds
+// and nack it otherwise.
+func (r *RabbitMQ) Retry(ctx context.Context, msg *amqp.Delivery, policy RetryPolicy, cause error) error {
+	attempt := RetryCount(msg.Headers) + 1
+
+	headers := amqp.Table{}
+	for key, value := range msg.Headers {
+		headers[key] = value
+	}
+	headers[LastErrorHeader] = cause.Error()
+
+	if policy.Exhausted(msg.Headers, cause) {
+		if policy.DeadLetterExchange == "" {
+			return fmt.Errorf("[RABBITMQ] - message can't be retried and no dead-letter exchange configured: %w", cause)
+		}
+
+		headers[RetryCountHeader] = int64(attempt - 1)
+		headers[OriginalExchangeHeader] = policy.Exchange
+		headers[OriginalRoutingKeyHeader] = policy.RoutingKey
+
+		if IsPermanent(cause) {
+			log.Printf("[RABBITMQ] - Permanent failure, dead-lettering message: %v", cause)
+		} else {
+			log.Printf("[RABBITMQ] - Retries exhausted after %d attempt(s), dead-lettering message: %v", attempt-1, cause)
+		}
+
+		return r.publish(ctx, policy.DeadLetterExchange, policy.DeadLetterRoutingKey, amqp.Publishing{
+			ContentType: msg.ContentType,
+			Body:        msg.Body,
+			Headers:     headers,
+		})
+	}
+
+	delay := policy.Delay(attempt)
+	headers[RetryCountHeader] = int64(attempt)
+	headers["x-delay"] = delay.Milliseconds()
+
+	log.Printf("[RABBITMQ] - Retrying message in %s (%d/%d): %v", delay, attempt, policy.MaxAttempts, cause)
+
+	return r.publish(ctx, policy.Exchange, policy.RoutingKey, amqp.Publishing{
+		ContentType: msg.ContentType,
+		Body:        msg.Body,
+		Headers:     headers,
+	})
+}
+
+// RetryCount returns how many times a message has already been retried.
+func RetryCount(headers amqp.Table) int {
+	switch count := headers[RetryCountHeader].(type) {
+	case int:
+		return count
+	case int32:
+		return int(count)
+	case int64:
+		return int(count)
+	}
+	return 0
+}
@@ -0,0 +1,190 @@
+package queue
+
+import (
+	config "afrus-whatsapp-evolution_api-notification/configs"
+	"errors"
+	"fmt"
+	"log"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	delayedMessageExchangeKind = "x-delayed-message"
+	defaultDelayedExchangeType = "direct"
+)
+
+type ExchangeDeclaration struct {
+	Name string
+	Kind string
+	Args amqp.Table
+}
+
+type Binding struct {
+	Exchange   string
+	RoutingKey string
+}
+
+type QueueDeclaration struct {
+	Name     string
+	Args     amqp.Table
+	Bindings []Binding
+}
+
+// Topology lists the exchanges, queues and bindings the service relies on so
+// it can bootstrap a fresh broker by itself.
+type Topology struct {
+	Exchanges []ExchangeDeclaration
+	Queues    []QueueDeclaration
+}
+
+// NewTopology builds the service topology from configs. Entries whose names
+// are not configured are skipped. The dead-letter arguments only apply to
+// queues the service creates; queues that already exist need a broker policy
+// instead.
+func NewTopology(configs *config.Config) *Topology {
+	delayedType := configs.RabbitMQDelayedExchangeType
+	if delayedType == "" {
+		delayedType = defaultDelayedExchangeType
+	}
+
+	topology := &Topology{}
+
+	topology.addExchange(ExchangeDeclaration{
+		Name: configs.EvolutionAPINotificationExchange,
+		Kind: delayedMessageExchangeKind,
+		Args: amqp.Table{"x-delayed-type": delayedType},
+	})
+	topology.addExchange(ExchangeDeclaration{
+		Name: configs.RabbitMQBillingExchange,
+		Kind: amqp.ExchangeDirect,
+	})
+	topology.addExchange(ExchangeDeclaration{
+		Name: configs.RabbitMQDeadLetterExchange,
+		Kind: amqp.ExchangeDirect,
+	})
+
+	var deadLetterArgs amqp.Table
+	if configs.RabbitMQDeadLetterExchange != "" {
+		deadLetterArgs = amqp.Table{
+			"x-dead-letter-exchange":    configs.RabbitMQDeadLetterExchange,
+			"x-dead-letter-routing-key": configs.RabbitMQDeadLetterRoutingKey,
+		}
+	}
+
+	topology.addQueue(QueueDeclaration{
+		Name:     configs.EvolutionAPINotificationBlastQueue,
+		Args:     deadLetterArgs,
+		Bindings: []Binding{{Exchange: configs.EvolutionAPINotificationExchange, RoutingKey: configs.EvolutionAPINotificationBlastRoutingKey}},
+	})
+	topology.addQueue(QueueDeclaration{
+		Name:     configs.EvolutionAPINotificationAutoresponderQueue,
+		Args:     deadLetterArgs,
+		Bindings: []Binding{{Exchange: configs.EvolutionAPINotificationExchange, RoutingKey: configs.EvolutionAPINotificationAutoresponderRoutingKey}},
+	})
+	topology.addQueue(QueueDeclaration{
+		Name:     configs.RabbitMQBillingQueue,
+		Bindings: []Binding{{Exchange: configs.RabbitMQBillingExchange, RoutingKey: configs.RabbitMQBillingRoutingKey}},
+	})
+	topology.addQueue(QueueDeclaration{
+		Name:     configs.RabbitMQDeadLetterQueue,
+		Bindings: []Binding{{Exchange: configs.RabbitMQDeadLetterExchange, RoutingKey: configs.RabbitMQDeadLetterRoutingKey}},
+	})
+
+	return topology
+}
+
+func (t *Topology) addExchange(exchange ExchangeDeclaration) {
+	if exchange.Name == "" {
+		return
+	}
+	t.Exchanges = append(t.Exchanges, exchange)
+}
+
+func (t *Topology) addQueue(queue QueueDeclaration) {
+	if queue.Name == "" {
+		return
+	}
+
+	bindings := queue.Bindings[:0]
+	for _, binding := range queue.Bindings {
+		if binding.Exchange != "" {
+			bindings = append(bindings, binding)
+		}
+	}
+	queue.Bindings = bindings
+
+	t.Queues = append(t.Queues, queue)
+}
+
+// SetTopology registers the topology declared on every (re)connection.
+func (r *RabbitMQ) SetTopology(topology *Topology) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.topology = topology
+}
+
+// declareTopology runs on its own channel: a mismatching declaration closes
+// the channel it was issued on and must not take the consumers down with it.
+func (r *RabbitMQ) declareTopology(conn *amqp.Connection) error {
+	if r.topology == nil {
+		return nil
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return fmt.Errorf("failed to open RabbitMQ topology channel: %w", err)
+	}
+	defer ch.Close()
+
+	for _, exchange := range r.topology.Exchanges {
+		if err := ch.ExchangeDeclare(exchange.Name, exchange.Kind, true, false, false, false, exchange.Args); err != nil {
+			return fmt.Errorf("failed to declare exchange %s: %w", exchange.Name, err)
+		}
+	}
+
+	for _, queue := range r.topology.Queues {
+		exists, err := queueExists(conn, queue.Name)
+		if err != nil {
+			return err
+		}
+
+		// Queue arguments can't change once declared, and redeclaring with
+		// different ones fails, so existing queues are kept as they are
+		if !exists {
+			if _, err := ch.QueueDeclare(queue.Name, true, false, false, false, queue.Args); err != nil {
+				return fmt.Errorf("failed to declare queue %s: %w", queue.Name, err)
+			}
+		} else if len(queue.Args) > 0 {
+			log.Printf("[RABBITMQ] - Queue %s already exists, keeping its arguments: apply the dead-letter exchange through a broker policy if it lacks them", queue.Name)
+		}
+		for _, binding := range queue.Bindings {
+			if err := ch.QueueBind(queue.Name, binding.RoutingKey, binding.Exchange, false, nil); err != nil {
+				return fmt.Errorf("failed to bind queue %s to %s: %w", queue.Name, binding.Exchange, err)
+			}
+		}
+	}
+
+	log.Printf("[RABBITMQ] - Topology declared: %d exchange(s), %d queue(s)", len(r.topology.Exchanges), len(r.topology.Queues))
+
+	return nil
+}
+
+// queueExists declares name passively on a channel of its own, since the
+// broker closes the channel when the queue doesn't exist.
+func queueExists(conn *amqp.Connection, name string) (bool, error) {
+	ch, err := conn.Channel()
+	if err != nil {
+		return false, fmt.Errorf("failed to open RabbitMQ topology channel: %w", err)
+	}
+	defer ch.Close()
+
+	if _, err := ch.QueueDeclarePassive(name, true, false, false, false, nil); err != nil {
+		var amqpErr *amqp.Error
+		if errors.As(err, &amqpErr) && amqpErr.Code == amqp.NotFound {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to inspect queue %s: %w", name, err)
+	}
+	return true, nil
+}
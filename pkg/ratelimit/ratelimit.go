@@ -0,0 +1,156 @@
+package ratelimit
+
+import (
+	"fmt"
+	"time"
+)
+
+// Policy combines a token bucket, which limits bursts and paces sends, with
+// sliding windows capping the sends per minute, hour and day. Zero values
+// disable the corresponding limit.
+type Policy struct {
+	BucketCapacity int
+	RefillInterval time.Duration
+	PerMinute      int
+	PerHour        int
+	PerDay         int
+}
+
+// State is the per-instance usage of a policy. It is stored between sends,
+// so it only holds JSON friendly fields.
+type State struct {
+	Tokens     float64            `json:"tokens"`
+	RefilledAt time.Time          `json:"refilled_at"`
+	Windows    map[string]*Window `json:"windows,omitempty"`
+}
+
+// Window approximates a sliding window with the counts of the current and
+// previous fixed windows, weighting the previous one by how much of it still
+// overlaps the sliding window.
+type Window struct {
+	Start    time.Time `json:"start"`
+	Count    int       `json:"count"`
+	Previous int       `json:"previous"`
+}
+
+// LimitError reports the limit that rejected a send and when a send will be
+// allowed again.
+type LimitError struct {
+	Limit      string
+	RetryAfter time.Duration
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s limit reached, retry in %s", e.Limit, e.RetryAfter.Round(time.Second))
+}
+
+type windowLimit struct {
+	name  string
+	size  time.Duration
+	limit int
+}
+
+func (p Policy) windows() []windowLimit {
+	return []windowLimit{
+		{name: "minute", size: time.Minute, limit: p.PerMinute},
+		{name: "hour", size: time.Hour, limit: p.PerHour},
+		{name: "day", size: 24 * time.Hour, limit: p.PerDay},
+	}
+}
+
+// Allow records a send at now when every limit allows it. Otherwise state is
+// left untouched and a *LimitError tells how long to wait.
+func (p Policy) Allow(state *State, now time.Time) error {
+	tokens := p.refill(state, now)
+	if p.BucketCapacity > 0 && tokens < 1 {
+		return &LimitError{
+			Limit:      "token bucket",
+			RetryAfter: time.Duration((1 - tokens) * float64(p.RefillInterval)),
+		}
+	}
+
+	for _, w := range p.windows() {
+		if w.limit <= 0 {
+			continue
+		}
+		window := rolled(state.Windows[w.name], w.size, now)
+		if window.estimate(w.size, now) >= float64(w.limit) {
+			return &LimitError{Limit: "per " + w.name, RetryAfter: window.retryAfter(w.size, w.limit, now)}
+		}
+	}
+
+	if p.BucketCapacity > 0 {
+		state.Tokens = tokens - 1
+		state.RefilledAt = now
+	}
+	for _, w := range p.windows() {
+		if w.limit <= 0 {
+			continue
+		}
+		if state.Windows == nil {
+			state.Windows = map[string]*Window{}
+		}
+		window := rolled(state.Windows[w.name], w.size, now)
+		window.Count++
+		state.Windows[w.name] = &window
+	}
+	return nil
+}
+
+// refill returns the tokens available at now. A new state starts full.
+func (p Policy) refill(state *State, now time.Time) float64 {
+	if p.BucketCapacity <= 0 {
+		return 0
+	}
+	if state.RefilledAt.IsZero() {
+		return float64(p.BucketCapacity)
+	}
+
+	tokens := state.Tokens
+	if p.RefillInterval > 0 && now.After(state.RefilledAt) {
+		tokens += float64(now.Sub(state.RefilledAt)) / float64(p.RefillInterval)
+	}
+	if tokens > float64(p.BucketCapacity) {
+		tokens = float64(p.BucketCapacity)
+	}
+	return tokens
+}
+
+// rolled returns the window aligned to the fixed window containing now.
+func rolled(window *Window, size time.Duration, now time.Time) Window {
+	start := now.Truncate(size)
+	if window == nil {
+		return Window{Start: start}
+	}
+
+	switch elapsed := start.Sub(window.Start); {
+	case elapsed <= 0:
+		return *window
+	case elapsed == size:
+		return Window{Start: start, Previous: window.Count}
+	default:
+		return Window{Start: start}
+	}
+}
+
+func (w Window) estimate(size time.Duration, now time.Time) float64 {
+	overlap := 1 - float64(now.Sub(w.Start))/float64(size)
+	return float64(w.Previous)*overlap + float64(w.Count)
+}
+
+// retryAfter returns when the estimate drops below limit, which is when the
+// previous window has slid out enough or, at the latest, the next window.
+func (w Window) retryAfter(size time.Duration, limit int, now time.Time) time.Duration {
+	next := w.Start.Add(size).Sub(now)
+	if w.Previous == 0 || w.Count >= limit {
+		return next
+	}
+
+	// previous * (1 - t/size) + count < limit  =>  t > size * (1 - (limit-count)/previous)
+	elapsed := time.Duration(float64(size) * (1 - float64(limit-w.Count)/float64(w.Previous)))
+	wait := w.Start.Add(elapsed).Sub(now) + time.Second
+	if wait <= 0 || wait > next {
+		return next
+	}
+	return wait
+}
@@ -0,0 +1,163 @@
+package ratelimit
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var base = time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+func TestAllow(t *testing.T) {
+	type send struct {
+		at         time.Duration
+		limit      string
+		retryAfter time.Duration
+	}
+	tests := []struct {
+		name   string
+		policy Policy
+		sends  []send
+	}{
+		{
+			name:   "new bucket starts full",
+			policy: Policy{BucketCapacity: 3, RefillInterval: time.Minute},
+			sends: []send{
+				{at: 0}, {at: 0}, {at: 0},
+				{at: 0, limit: "token bucket", retryAfter: time.Minute},
+			},
+		},
+		{
+			name:   "bucket refills over time",
+			policy: Policy{BucketCapacity: 2, RefillInterval: time.Minute},
+			sends: []send{
+				{at: 0}, {at: 0},
+				{at: 30 * time.Second, limit: "token bucket", retryAfter: 30 * time.Second},
+				{at: time.Minute},
+				{at: time.Minute, limit: "token bucket", retryAfter: time.Minute},
+			},
+		},
+		{
+			name:   "bucket refill is capped at capacity",
+			policy: Policy{BucketCapacity: 1, RefillInterval: time.Minute},
+			sends: []send{
+				{at: 0},
+				{at: time.Hour},
+				{at: time.Hour, limit: "token bucket", retryAfter: time.Minute},
+			},
+		},
+		{
+			name:   "per minute waits for the next window",
+			policy: Policy{PerMinute: 2},
+			sends: []send{
+				{at: 10 * time.Second}, {at: 20 * time.Second},
+				{at: 30 * time.Second, limit: "per minute", retryAfter: 30 * time.Second},
+			},
+		},
+		{
+			name:   "per minute weights the previous window",
+			policy: Policy{PerMinute: 2},
+			sends: []send{
+				{at: 50 * time.Second}, {at: 55 * time.Second},
+				// 2 previous sends still fully overlap the sliding window
+				{at: time.Minute, limit: "per minute", retryAfter: time.Second},
+				// Half of them have slid out: 2 * 0.5 + 0 < 2
+				{at: 90 * time.Second},
+				// 2 * 0.25 + 1 < 2
+				{at: 105 * time.Second},
+				{at: 110 * time.Second, limit: "per minute", retryAfter: 10 * time.Second},
+			},
+		},
+		{
+			name:   "previous window is dropped after a gap",
+			policy: Policy{PerMinute: 1},
+			sends: []send{
+				{at: 0},
+				{at: 2 * time.Minute},
+			},
+		},
+		{
+			name:   "per hour",
+			policy: Policy{PerHour: 2},
+			sends: []send{
+				{at: 0}, {at: time.Minute},
+				{at: 15 * time.Minute, limit: "per hour", retryAfter: 45 * time.Minute},
+			},
+		},
+		{
+			name:   "per day",
+			policy: Policy{PerDay: 1},
+			sends: []send{
+				{at: 0},
+				{at: time.Hour, limit: "per day", retryAfter: 11 * time.Hour},
+			},
+		},
+		{
+			name:   "bucket is checked before the windows",
+			policy: Policy{BucketCapacity: 1, RefillInterval: time.Hour, PerMinute: 1},
+			sends: []send{
+				{at: 0},
+				{at: time.Second, limit: "token bucket", retryAfter: time.Hour - time.Second},
+			},
+		},
+		{
+			name:   "zero policy allows everything",
+			policy: Policy{},
+			sends:  []send{{at: 0}, {at: 0}, {at: 0}, {at: 0}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var state State
+			for i, s := range tt.sends {
+				err := tt.policy.Allow(&state, base.Add(s.at))
+				if s.limit == "" {
+					if err != nil {
+						t.Fatalf("send %d at %s: unexpected error: %v", i, s.at, err)
+					}
+					continue
+				}
+
+				var limitErr *LimitError
+				if !errors.As(err, &limitErr) {
+					t.Fatalf("send %d at %s: got %v, want %s limit", i, s.at, err, s.limit)
+				}
+				if limitErr.Limit != s.limit {
+					t.Errorf("send %d at %s: limit = %q, want %q", i, s.at, limitErr.Limit, s.limit)
+				}
+				if limitErr.RetryAfter != s.retryAfter {
+					t.Errorf("send %d at %s: retry after %s, want %s", i, s.at, limitErr.RetryAfter, s.retryAfter)
+				}
+			}
+		})
+	}
+}
+
+func TestAllowRejectLeavesStateUntouched(t *testing.T) {
+	policy := Policy{BucketCapacity: 2, RefillInterval: time.Minute, PerMinute: 1, PerHour: 10}
+
+	var state State
+	if err := policy.Allow(&state, base); err != nil {
+		t.Fatalf("first send: unexpected error: %v", err)
+	}
+
+	before := cloneState(state)
+	if err := policy.Allow(&state, base.Add(time.Second)); err == nil {
+		t.Fatal("second send: expected the per minute limit")
+	}
+	if !reflect.DeepEqual(state, before) {
+		t.Errorf("rejected send changed state from %+v to %+v", before, state)
+	}
+}
+
+func cloneState(state State) State {
+	clone := state
+	clone.Windows = map[string]*Window{}
+	for name, window := range state.Windows {
+		w := *window
+		clone.Windows[name] = &w
+	}
+	return clone
+}
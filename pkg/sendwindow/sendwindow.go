@@ -0,0 +1,97 @@
+package sendwindow
+
+import (
+	"fmt"
+	"time"
+)
+
+// Window is a daily local-time range in which messages may be sent. A window
+// whose end is before its start spans midnight (e.g. 22:00-06:00).
+type Window struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// Parse builds a window from "HH:MM" bounds. Empty bounds yield the zero
+// window, which is always open.
+func Parse(start, end string) (Window, error) {
+	if start == "" && end == "" {
+		return Window{}, nil
+	}
+
+	startOffset, err := parseClock(start)
+	if err != nil {
+		return Window{}, err
+	}
+	endOffset, err := parseClock(end)
+	if err != nil {
+		return Window{}, err
+	}
+
+	return Window{Start: startOffset, End: endOffset}, nil
+}
+
+func parseClock(value string) (time.Duration, error) {
+	clock, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid send window time %q: %w", value, err)
+	}
+	return time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute, nil
+}
+
+func (w Window) IsZero() bool {
+	return w.Start == w.End
+}
+
+// Contains reports whether t, in its own location, falls inside the window.
+func (w Window) Contains(t time.Time) bool {
+	if w.IsZero() {
+		return true
+	}
+
+	offset := sinceMidnight(t)
+	if w.Start < w.End {
+		return offset >= w.Start && offset < w.End
+	}
+	return offset >= w.Start || offset < w.End
+}
+
+// NextOpening returns t when the window is open, or the next time it opens
+// in t's location.
+func (w Window) NextOpening(t time.Time) time.Time {
+	if w.Contains(t) {
+		return t
+	}
+
+	opening := clockOn(t, 0, w.Start)
+	if !opening.After(t) {
+		opening = clockOn(t, 1, w.Start)
+	}
+	return opening
+}
+
+// Closing returns when the window open at t closes next. It returns the zero
+// time for the zero window, which never closes.
+func (w Window) Closing(t time.Time) time.Time {
+	if w.IsZero() {
+		return time.Time{}
+	}
+
+	closing := clockOn(t, 0, w.End)
+	if !closing.After(t) {
+		closing = clockOn(t, 1, w.End)
+	}
+	return closing
+}
+
+// clockOn returns the wall clock offset on t's date plus days, in t's
+// location. Building it from the hour and minute, rather than adding offset to
+// midnight, keeps it right on days with a DST transition.
+func clockOn(t time.Time, days int, offset time.Duration) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day+days, int(offset/time.Hour), int(offset%time.Hour/time.Minute), 0, 0, t.Location())
+}
+
+func sinceMidnight(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+}
@@ -0,0 +1,182 @@
+package sendwindow
+
+import (
+	"testing"
+	"time"
+)
+
+func mustLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("error loading %s: %v", name, err)
+	}
+	return location
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name       string
+		start, end string
+		want       Window
+		wantErr    bool
+	}{
+		{name: "empty", want: Window{}},
+		{name: "daytime", start: "09:00", end: "18:30", want: Window{Start: 9 * time.Hour, End: 18*time.Hour + 30*time.Minute}},
+		{name: "across midnight", start: "22:00", end: "06:00", want: Window{Start: 22 * time.Hour, End: 6 * time.Hour}},
+		{name: "missing end", start: "09:00", wantErr: true},
+		{name: "invalid hour", start: "25:00", end: "18:00", wantErr: true},
+		{name: "not a time", start: "nine", end: "18:00", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.start, tt.end)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Parse(%q, %q) = %+v, want an error", tt.start, tt.end, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q, %q) returned error: %v", tt.start, tt.end, err)
+			}
+			if got != tt.want {
+				t.Errorf("Parse(%q, %q) = %+v, want %+v", tt.start, tt.end, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWindow(t *testing.T) {
+	newYork := mustLocation(t, "America/New_York")
+	london := mustLocation(t, "Europe/London")
+	daytime := Window{Start: 9 * time.Hour, End: 18 * time.Hour}
+	overnight := Window{Start: 22 * time.Hour, End: 6 * time.Hour}
+
+	tests := []struct {
+		name        string
+		window      Window
+		at          time.Time
+		contains    bool
+		nextOpening time.Time
+		closing     time.Time
+	}{
+		{
+			name:        "zero window is always open",
+			window:      Window{},
+			at:          time.Date(2026, 3, 10, 3, 0, 0, 0, time.UTC),
+			contains:    true,
+			nextOpening: time.Date(2026, 3, 10, 3, 0, 0, 0, time.UTC),
+		},
+		{
+			name:        "inside daytime window",
+			window:      daytime,
+			at:          time.Date(2026, 3, 10, 12, 0, 0, 0, newYork),
+			contains:    true,
+			nextOpening: time.Date(2026, 3, 10, 12, 0, 0, 0, newYork),
+			closing:     time.Date(2026, 3, 10, 18, 0, 0, 0, newYork),
+		},
+		{
+			name:        "before daytime window",
+			window:      daytime,
+			at:          time.Date(2026, 3, 10, 8, 59, 59, 0, newYork),
+			nextOpening: time.Date(2026, 3, 10, 9, 0, 0, 0, newYork),
+			closing:     time.Date(2026, 3, 10, 18, 0, 0, 0, newYork),
+		},
+		{
+			name:        "end is exclusive",
+			window:      daytime,
+			at:          time.Date(2026, 3, 10, 18, 0, 0, 0, newYork),
+			nextOpening: time.Date(2026, 3, 11, 9, 0, 0, 0, newYork),
+			closing:     time.Date(2026, 3, 11, 18, 0, 0, 0, newYork),
+		},
+		{
+			name:        "overnight window before midnight",
+			window:      overnight,
+			at:          time.Date(2026, 3, 10, 23, 0, 0, 0, newYork),
+			contains:    true,
+			nextOpening: time.Date(2026, 3, 10, 23, 0, 0, 0, newYork),
+			closing:     time.Date(2026, 3, 11, 6, 0, 0, 0, newYork),
+		},
+		{
+			name:        "overnight window after midnight",
+			window:      overnight,
+			at:          time.Date(2026, 3, 11, 3, 0, 0, 0, newYork),
+			contains:    true,
+			nextOpening: time.Date(2026, 3, 11, 3, 0, 0, 0, newYork),
+			closing:     time.Date(2026, 3, 11, 6, 0, 0, 0, newYork),
+		},
+		{
+			name:        "overnight window closed at noon",
+			window:      overnight,
+			at:          time.Date(2026, 3, 11, 12, 0, 0, 0, newYork),
+			nextOpening: time.Date(2026, 3, 11, 22, 0, 0, 0, newYork),
+			closing:     time.Date(2026, 3, 12, 6, 0, 0, 0, newYork),
+		},
+		{
+			// Clocks go forward at 02:00 on March 8, 2026
+			name:        "opening on spring forward day",
+			window:      daytime,
+			at:          time.Date(2026, 3, 7, 20, 0, 0, 0, newYork),
+			nextOpening: time.Date(2026, 3, 8, 13, 0, 0, 0, time.UTC),
+			closing:     time.Date(2026, 3, 8, 22, 0, 0, 0, time.UTC),
+		},
+		{
+			// Clocks go back at 02:00 on November 1, 2026
+			name:        "opening on fall back day",
+			window:      daytime,
+			at:          time.Date(2026, 10, 31, 20, 0, 0, 0, newYork),
+			nextOpening: time.Date(2026, 11, 1, 14, 0, 0, 0, time.UTC),
+			closing:     time.Date(2026, 11, 1, 23, 0, 0, 0, time.UTC),
+		},
+		{
+			// Clocks go forward at 01:00 on March 29, 2026
+			name:        "overnight window closing on spring forward day",
+			window:      overnight,
+			at:          time.Date(2026, 3, 28, 23, 0, 0, 0, london),
+			contains:    true,
+			nextOpening: time.Date(2026, 3, 28, 23, 0, 0, 0, london),
+			closing:     time.Date(2026, 3, 29, 5, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.window.Contains(tt.at); got != tt.contains {
+				t.Errorf("Contains(%s) = %v, want %v", tt.at, got, tt.contains)
+			}
+			if got := tt.window.NextOpening(tt.at); !got.Equal(tt.nextOpening) {
+				t.Errorf("NextOpening(%s) = %s, want %s", tt.at, got, tt.nextOpening)
+			}
+			if got := tt.window.Closing(tt.at); !got.Equal(tt.closing) {
+				t.Errorf("Closing(%s) = %s, want %s", tt.at, got, tt.closing)
+			}
+		})
+	}
+}
+
+func TestResolveLocation(t *testing.T) {
+	tests := []struct {
+		name         string
+		leadTimezone string
+		phone        string
+		fallback     string
+		want         string
+	}{
+		{name: "lead timezone", leadTimezone: "Europe/Madrid", phone: "+5511987654321", fallback: "America/Lima", want: "Europe/Madrid"},
+		{name: "invalid lead timezone uses phone", leadTimezone: "Mars/Base", phone: "+5511987654321", want: "America/Sao_Paulo"},
+		{name: "three digit calling code first", phone: "+59171234567", want: "America/La_Paz"},
+		{name: "one digit calling code", phone: "14155552671", want: "America/New_York"},
+		{name: "unknown calling code uses fallback", phone: "+99912345678", fallback: "America/Lima", want: "America/Lima"},
+		{name: "defaults to UTC", phone: "+99912345678", fallback: "Nowhere/City", want: "UTC"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ResolveLocation(tt.leadTimezone, tt.phone, tt.fallback); got.String() != tt.want {
+				t.Errorf("ResolveLocation(%q, %q, %q) = %s, want %s", tt.leadTimezone, tt.phone, tt.fallback, got, tt.want)
+			}
+		})
+	}
+}
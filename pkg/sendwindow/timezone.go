@@ -0,0 +1,77 @@
+package sendwindow
+
+import (
+	"strings"
+	"time"
+	_ "time/tzdata" // the production image ships without a zoneinfo database
+	"unicode"
+)
+
+// countryTimezones maps calling codes to the timezone of the most populated
+// region of the country. Longer codes are matched first.
+var countryTimezones = map[string]string{
+	"1":   "America/New_York",
+	"33":  "Europe/Paris",
+	"34":  "Europe/Madrid",
+	"39":  "Europe/Rome",
+	"44":  "Europe/London",
+	"49":  "Europe/Berlin",
+	"51":  "America/Lima",
+	"52":  "America/Mexico_City",
+	"53":  "America/Havana",
+	"54":  "America/Argentina/Buenos_Aires",
+	"55":  "America/Sao_Paulo",
+	"56":  "America/Santiago",
+	"57":  "America/Bogota",
+	"58":  "America/Caracas",
+	"351": "Europe/Lisbon",
+	"502": "America/Guatemala",
+	"503": "America/El_Salvador",
+	"504": "America/Tegucigalpa",
+	"505": "America/Managua",
+	"506": "America/Costa_Rica",
+	"507": "America/Panama",
+	"591": "America/La_Paz",
+	"593": "America/Guayaquil",
+	"595": "America/Asuncion",
+	"598": "America/Montevideo",
+}
+
+// TimezoneForPhone guesses the timezone of an international phone number from
+// its calling code.
+func TimezoneForPhone(phone string) (*time.Location, bool) {
+	digits := strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, phone)
+
+	for length := 3; length >= 1; length-- {
+		if len(digits) <= length {
+			continue
+		}
+		if name, ok := countryTimezones[digits[:length]]; ok {
+			if location, err := time.LoadLocation(name); err == nil {
+				return location, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// ResolveLocation returns the first valid location among the stored lead
+// timezone, the one derived from the phone and the organization fallback,
+// defaulting to UTC.
+func ResolveLocation(leadTimezone, phone, fallbackTimezone string) *time.Location {
+	if location, err := time.LoadLocation(leadTimezone); leadTimezone != "" && err == nil {
+		return location
+	}
+	if location, ok := TimezoneForPhone(phone); ok {
+		return location
+	}
+	if location, err := time.LoadLocation(fallbackTimezone); fallbackTimezone != "" && err == nil {
+		return location
+	}
+	return time.UTC
+}
@@ -0,0 +1,51 @@
+package shortlink
+
+import (
+	"afrus-whatsapp-evolution_api-notification/pkg/urls"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+const (
+	codeLength   = 8
+	codeAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+)
+
+// NewCode returns a random base62 code for a short link.
+func NewCode() (string, error) {
+	code := make([]byte, codeLength)
+	max := big.NewInt(int64(len(codeAlphabet)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate short link code: %w", err)
+		}
+		code[i] = codeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+func BuildURL(baseURL, code string) string {
+	return fmt.Sprintf("%s/%s", strings.TrimSuffix(baseURL, "/"), code)
+}
+
+// Shorten replaces every URL in text by a short link under baseURL. create
+// persists the original link and returns its code. Links already pointing to
+// baseURL are kept as they are.
+func Shorten(text, baseURL string, create func(link string) (string, error)) (string, error) {
+	prefix := strings.TrimSuffix(baseURL, "/") + "/"
+
+	return urls.Replace(text, func(link string) (string, error) {
+		if strings.HasPrefix(link, prefix) {
+			return link, nil
+		}
+
+		code, err := create(link)
+		if err != nil {
+			return "", err
+		}
+		return BuildURL(baseURL, code), nil
+	})
+}
@@ -0,0 +1,50 @@
+package template
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var ErrMissingVariable = errors.New("missing template variable")
+
+// placeholderPattern matches {{name}} and {{name | default:"fallback"}}.
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.]+)\s*(?:\|\s*default\s*:\s*"((?:[^"\\]|\\.)*)"\s*)?\}\}`)
+
+type Variables map[string]string
+
+// Render replaces every placeholder in content with its value from vars.
+// Empty or unknown variables use their default when one is given; otherwise
+// rendering fails with ErrMissingVariable listing every missing name.
+func Render(content string, vars Variables) (string, error) {
+	missing := make(map[string]struct{})
+
+	rendered := placeholderPattern.ReplaceAllStringFunc(content, func(placeholder string) string {
+		match := placeholderPattern.FindStringSubmatch(placeholder)
+		name := match[1]
+		hasDefault := strings.Contains(placeholder, "|")
+
+		if value := vars[name]; value != "" {
+			return value
+		}
+		if hasDefault {
+			return strings.ReplaceAll(match[2], `\"`, `"`)
+		}
+
+		missing[name] = struct{}{}
+		return placeholder
+	})
+
+	if len(missing) > 0 {
+		names := make([]string, 0, len(missing))
+		for name := range missing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return "", fmt.Errorf("%w: %s", ErrMissingVariable, strings.Join(names, ", "))
+	}
+
+	return rendered, nil
+}
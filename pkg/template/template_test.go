@@ -0,0 +1,70 @@
+package template
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	vars := Variables{
+		"first_name": "Ana",
+		"city":       "Lima",
+		"empty":      "",
+		"lead.email": "ana@example.com",
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "no placeholders", content: "Hello!", want: "Hello!"},
+		{name: "variable", content: "Hi {{first_name}}", want: "Hi Ana"},
+		{name: "spaces inside braces", content: "Hi {{ first_name }}", want: "Hi Ana"},
+		{name: "repeated variable", content: "{{city}}, {{city}}", want: "Lima, Lima"},
+		{name: "dotted name", content: "Mail: {{lead.email}}", want: "Mail: ana@example.com"},
+		{name: "default unused when set", content: `Hi {{first_name | default:"friend"}}`, want: "Hi Ana"},
+		{name: "default for unknown variable", content: `Hi {{nickname | default:"friend"}}`, want: "Hi friend"},
+		{name: "default for empty variable", content: `Hi {{empty | default:"friend"}}`, want: "Hi friend"},
+		{name: "empty default", content: `Hi{{nickname | default:""}}!`, want: "Hi!"},
+		{name: "default with escaped quote", content: `{{nickname | default:"the \"best\" donor"}}`, want: `the "best" donor`},
+		{name: "default without spaces", content: `{{nickname|default:"friend"}}`, want: "friend"},
+		{name: "single braces are text", content: "{first_name}", want: "{first_name}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(tt.content, vars)
+			if err != nil {
+				t.Fatalf("Render(%q) returned error: %v", tt.content, err)
+			}
+			if got != tt.want {
+				t.Errorf("Render(%q) = %q, want %q", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderMissing(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "unknown variable", content: "Hi {{nickname}}", want: "missing template variable: nickname"},
+		{name: "empty variable", content: "Hi {{empty}}", want: "missing template variable: empty"},
+		{name: "every missing name sorted once", content: "{{zip}} {{age}} {{zip}}", want: "missing template variable: age, zip"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(tt.content, Variables{"empty": ""})
+			if !errors.Is(err, ErrMissingVariable) {
+				t.Fatalf("Render(%q) = %q, %v, want ErrMissingVariable", tt.content, got, err)
+			}
+			if err.Error() != tt.want {
+				t.Errorf("Render(%q) error = %q, want %q", tt.content, err.Error(), tt.want)
+			}
+		})
+	}
+}
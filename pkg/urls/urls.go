@@ -0,0 +1,37 @@
+package urls
+
+import (
+	"regexp"
+	"strings"
+)
+
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"]+`)
+
+// trailingPunctuation is stripped from matched URLs since it usually belongs
+// to the surrounding sentence ("visit https://afrus.org.").
+const trailingPunctuation = `.,;:!?)]}'"`
+
+// Replace calls fn for every http(s) URL found in text and substitutes the
+// URL with its result. The first error aborts the replacement.
+func Replace(text string, fn func(link string) (string, error)) (string, error) {
+	var replaceErr error
+
+	replaced := urlPattern.ReplaceAllStringFunc(text, func(match string) string {
+		if replaceErr != nil {
+			return match
+		}
+
+		link := strings.TrimRight(match, trailingPunctuation)
+		replacement, err := fn(link)
+		if err != nil {
+			replaceErr = err
+			return match
+		}
+		return replacement + match[len(link):]
+	})
+
+	if replaceErr != nil {
+		return "", replaceErr
+	}
+	return replaced, nil
+}
@@ -0,0 +1,55 @@
+package urls
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestReplace(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "no links", text: "Hello there", want: "Hello there"},
+		{name: "bare link", text: "https://afrus.org/donar", want: "[https://afrus.org/donar]"},
+		{name: "http link", text: "see http://afrus.org", want: "see [http://afrus.org]"},
+		{name: "trailing period", text: "Visit https://afrus.org.", want: "Visit [https://afrus.org]."},
+		{name: "trailing punctuation run", text: "(https://afrus.org/x?a=1)!", want: "([https://afrus.org/x?a=1])!"},
+		{name: "fragment kept", text: "https://afrus.org/donar#form, thanks", want: "[https://afrus.org/donar#form], thanks"},
+		{name: "quoted link", text: `"https://afrus.org"`, want: `"[https://afrus.org]"`},
+		{name: "several links", text: "https://a.org https://b.org", want: "[https://a.org] [https://b.org]"},
+		{name: "stops at angle brackets", text: "<https://afrus.org>", want: "<[https://afrus.org]>"},
+		{name: "other schemes ignored", text: "ftp://afrus.org mailto:info@afrus.org", want: "ftp://afrus.org mailto:info@afrus.org"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Replace(tt.text, func(link string) (string, error) {
+				return "[" + link + "]", nil
+			})
+			if err != nil {
+				t.Fatalf("Replace(%q) returned error: %v", tt.text, err)
+			}
+			if got != tt.want {
+				t.Errorf("Replace(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplaceStopsAtFirstError(t *testing.T) {
+	errShorten := errors.New("shorten failed")
+	calls := 0
+
+	_, err := Replace("https://a.org https://b.org", func(link string) (string, error) {
+		calls++
+		return "", errShorten
+	})
+	if !errors.Is(err, errShorten) {
+		t.Fatalf("got %v, want %v", err, errShorten)
+	}
+	if calls != 1 {
+		t.Errorf("fn called %d times, want 1", calls)
+	}
+}
@@ -0,0 +1,87 @@
+package utm
+
+import (
+	"afrus-whatsapp-evolution_api-notification/pkg/urls"
+	"net/url"
+	"strings"
+)
+
+type Params struct {
+	Source   string
+	Medium   string
+	Campaign string
+	Content  string
+}
+
+// NewParams builds Params from the optional GA columns stored on triggers and
+// communications.
+func NewParams(source, medium, campaign, content *string) Params {
+	return Params{
+		Source:   deref(source),
+		Medium:   deref(medium),
+		Campaign: deref(campaign),
+		Content:  deref(content),
+	}
+}
+
+func (p Params) IsEmpty() bool {
+	return p.Source == "" && p.Medium == "" && p.Campaign == "" && p.Content == ""
+}
+
+// TagURL appends the UTM parameters to raw, keeping its existing query string
+// and fragment. Parameters already present in the URL are left untouched.
+func TagURL(raw string, p Params) string {
+	if p.IsEmpty() {
+		return raw
+	}
+
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" {
+		return raw
+	}
+
+	existing := parsed.Query()
+	tags := url.Values{}
+	for key, value := range map[string]string{
+		"utm_source":   p.Source,
+		"utm_medium":   p.Medium,
+		"utm_campaign": p.Campaign,
+		"utm_content":  p.Content,
+	} {
+		if value == "" || existing.Has(key) {
+			continue
+		}
+		tags.Set(key, value)
+	}
+
+	if len(tags) == 0 {
+		return raw
+	}
+
+	if parsed.RawQuery == "" {
+		parsed.RawQuery = tags.Encode()
+	} else {
+		parsed.RawQuery = parsed.RawQuery + "&" + tags.Encode()
+	}
+
+	return parsed.String()
+}
+
+// TagText tags every http(s) URL found in text.
+func TagText(text string, p Params) string {
+	if p.IsEmpty() {
+		return text
+	}
+
+	tagged, _ := urls.Replace(text, func(link string) (string, error) {
+		return TagURL(link, p), nil
+	})
+	return tagged
+}
+
+func deref(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return strings.TrimSpace(*value)
+}
@@ -0,0 +1,69 @@
+package utm
+
+import "testing"
+
+func TestTagURL(t *testing.T) {
+	params := Params{Source: "whatsapp", Medium: "blast"}
+
+	tests := []struct {
+		name   string
+		raw    string
+		params Params
+		want   string
+	}{
+		{name: "no query", raw: "https://afrus.org/donar", params: params, want: "https://afrus.org/donar?utm_medium=blast&utm_source=whatsapp"},
+		{name: "existing query", raw: "https://afrus.org/donar?amount=10", params: params, want: "https://afrus.org/donar?amount=10&utm_medium=blast&utm_source=whatsapp"},
+		{name: "fragment stays last", raw: "https://afrus.org/donar#form", params: params, want: "https://afrus.org/donar?utm_medium=blast&utm_source=whatsapp#form"},
+		{name: "query and fragment", raw: "https://afrus.org/donar?amount=10#form", params: params, want: "https://afrus.org/donar?amount=10&utm_medium=blast&utm_source=whatsapp#form"},
+		{name: "existing parameters win", raw: "https://afrus.org/?utm_source=email", params: params, want: "https://afrus.org/?utm_source=email&utm_medium=blast"},
+		{name: "every parameter present", raw: "https://afrus.org/?utm_source=a&utm_medium=b", params: params, want: "https://afrus.org/?utm_source=a&utm_medium=b"},
+		{name: "values are encoded", raw: "https://afrus.org", params: Params{Campaign: "Fin de año"}, want: "https://afrus.org?utm_campaign=Fin+de+a%C3%B1o"},
+		{name: "empty params", raw: "https://afrus.org/donar", params: Params{}, want: "https://afrus.org/donar"},
+		{name: "relative URL", raw: "/donar", params: params, want: "/donar"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TagURL(tt.raw, tt.params); got != tt.want {
+				t.Errorf("TagURL(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTagText(t *testing.T) {
+	params := Params{Source: "whatsapp"}
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "no links", text: "Thanks for your donation!", want: "Thanks for your donation!"},
+		{name: "trailing period", text: "Visit https://afrus.org/donar.", want: "Visit https://afrus.org/donar?utm_source=whatsapp."},
+		{name: "trailing question mark", text: "Seen https://afrus.org?", want: "Seen https://afrus.org?utm_source=whatsapp?"},
+		{name: "inside parentheses", text: "(https://afrus.org/donar)", want: "(https://afrus.org/donar?utm_source=whatsapp)"},
+		{name: "fragment before punctuation", text: "Go to https://afrus.org/donar#form!", want: "Go to https://afrus.org/donar?utm_source=whatsapp#form!"},
+		{name: "several links", text: "https://a.org and http://b.org/x", want: "https://a.org?utm_source=whatsapp and http://b.org/x?utm_source=whatsapp"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TagText(tt.text, params); got != tt.want {
+				t.Errorf("TagText(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewParams(t *testing.T) {
+	source, empty := " whatsapp ", ""
+	params := NewParams(&source, nil, &empty, nil)
+
+	if params != (Params{Source: "whatsapp"}) {
+		t.Errorf("NewParams = %+v, want only the trimmed source", params)
+	}
+	if !NewParams(nil, nil, &empty, nil).IsEmpty() {
+		t.Error("params without values should be empty")
+	}
+}
@@ -0,0 +1,76 @@
+package warmup
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Schedule lists the daily send caps of an instance for each warm-up level.
+// An instance climbs one level per healthy day of sending and stays on the
+// last level once it reaches it.
+type Schedule []int
+
+var DefaultSchedule = Schedule{20, 40, 60, 100, 150, 200, 300, 400, 500, 750, 1000}
+
+// Demotion thresholds: an instance whose failure rate exceeds MaxFailureRate
+// over at least MinFailureSamples sends in a day loses half of its levels.
+const (
+	MinFailureSamples = 20
+	MaxFailureRate    = 0.25
+)
+
+// ParseSchedule reads a comma separated list of daily caps (e.g.
+// "20,40,80,160"). An empty value returns the default schedule.
+func ParseSchedule(value string) (Schedule, error) {
+	if strings.TrimSpace(value) == "" {
+		return DefaultSchedule, nil
+	}
+
+	var schedule Schedule
+	for _, field := range strings.Split(value, ",") {
+		dailyCap, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || dailyCap <= 0 {
+			return nil, fmt.Errorf("invalid warm-up daily cap %q", field)
+		}
+		if len(schedule) > 0 && dailyCap < schedule[len(schedule)-1] {
+			return nil, fmt.Errorf("warm-up daily caps must not decrease: %d after %d", dailyCap, schedule[len(schedule)-1])
+		}
+		schedule = append(schedule, dailyCap)
+	}
+	return schedule, nil
+}
+
+func (s Schedule) MaxLevel() int {
+	return len(s) - 1
+}
+
+// Clamp keeps level within the schedule.
+func (s Schedule) Clamp(level int) int {
+	if level < 0 {
+		return 0
+	}
+	if level > s.MaxLevel() {
+		return s.MaxLevel()
+	}
+	return level
+}
+
+func (s Schedule) DailyCap(level int) int {
+	return s[s.Clamp(level)]
+}
+
+// Unhealthy reports whether a day with sent messages and failed ones should
+// demote the instance.
+func Unhealthy(sent, failed int) bool {
+	if sent < MinFailureSamples {
+		return false
+	}
+	return float64(failed)/float64(sent) > MaxFailureRate
+}
+
+// Demote returns the level an instance drops to after a ban signal or an
+// unhealthy day.
+func Demote(level int) int {
+	return level / 2
+}